@@ -0,0 +1,222 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2024 Arsene Tochemey Gandote
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package gokv
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/tochemey/gokv/internal/internalpb"
+	mocks "github.com/tochemey/gokv/mocks/discovery"
+)
+
+// benchmarkKeys is the number of distinct keys used by the benchmarks
+const benchmarkKeys = 10_000
+
+// benchmarkNode creates a node serving the KVService calls
+// without joining a cluster and fills its local state.
+// The node keeps its default events subscription, hence the writes publish their key events
+func benchmarkNode(b *testing.B) *Node {
+	b.Helper()
+	node, err := newNode(joinConfig(new(mocks.Provider)))
+	require.NoError(b, err)
+	node.started.Store(true)
+
+	ctx := context.Background()
+	value := make([]byte, 128)
+	for i := 0; i < benchmarkKeys; i++ {
+		_, err := node.Put(ctx, connect.NewRequest(&internalpb.PutRequest{Key: strconv.Itoa(i), Value: value}))
+		require.NoError(b, err)
+	}
+	return node
+}
+
+// lockedNode is the baseline the node is measured against. It emulates the node before its local state
+// was sharded: every KVService call and the marshalling of the whole local state for the push/pull
+// were serialized by a single mutex
+type lockedNode struct {
+	mu   sync.Mutex
+	node *Node
+}
+
+// put writes the given key/value pair holding the node mutex
+func (locked *lockedNode) put(ctx context.Context, request *connect.Request[internalpb.PutRequest]) {
+	locked.mu.Lock()
+	_, _ = locked.node.Put(ctx, request)
+	locked.mu.Unlock()
+}
+
+// get reads the given key holding the node mutex
+func (locked *lockedNode) get(ctx context.Context, request *connect.Request[internalpb.GetRequest]) {
+	locked.mu.Lock()
+	_, _ = locked.node.Get(ctx, request)
+	locked.mu.Unlock()
+}
+
+// list lists the entries holding the node mutex
+func (locked *lockedNode) list(ctx context.Context) {
+	locked.mu.Lock()
+	_, _ = locked.node.List(ctx, connect.NewRequest(new(internalpb.ListRequest)))
+	locked.mu.Unlock()
+}
+
+// pushPull marshals the whole local state holding the node mutex
+func (locked *lockedNode) pushPull() {
+	locked.mu.Lock()
+	_, _ = proto.Marshal(locked.node.delegate.local.snapshot())
+	locked.mu.Unlock()
+}
+
+// runParallel runs the given call on every benchmark goroutine with an increasing counter
+func runParallel(b *testing.B, call func(ctx context.Context, i int)) {
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		ctx := context.Background()
+		i := 0
+		for pb.Next() {
+			i++
+			call(ctx, i)
+		}
+	})
+	b.StopTimer()
+}
+
+// runInBackground runs the given calls in a loop, each in its own goroutine, until the returned function is called
+func runInBackground(calls ...func()) func() {
+	stop := make(chan struct{})
+	wg := new(sync.WaitGroup)
+	for _, call := range calls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					call()
+				}
+			}
+		}()
+	}
+
+	return func() {
+		close(stop)
+		wg.Wait()
+	}
+}
+
+func BenchmarkNodePut(b *testing.B) {
+	node := benchmarkNode(b)
+	locked := &lockedNode{node: node}
+	value := make([]byte, 128)
+	newRequest := func(i int) *connect.Request[internalpb.PutRequest] {
+		return connect.NewRequest(&internalpb.PutRequest{Key: strconv.Itoa(i % benchmarkKeys), Value: value})
+	}
+
+	b.Run("Sharded", func(b *testing.B) {
+		runParallel(b, func(ctx context.Context, i int) {
+			_, _ = node.Put(ctx, newRequest(i))
+		})
+	})
+	b.Run("Locked", func(b *testing.B) {
+		runParallel(b, func(ctx context.Context, i int) {
+			locked.put(ctx, newRequest(i))
+		})
+	})
+}
+
+func BenchmarkNodeGet(b *testing.B) {
+	node := benchmarkNode(b)
+	locked := &lockedNode{node: node}
+	newRequest := func(i int) *connect.Request[internalpb.GetRequest] {
+		return connect.NewRequest(&internalpb.GetRequest{Key: strconv.Itoa(i % benchmarkKeys)})
+	}
+
+	b.Run("Sharded", func(b *testing.B) {
+		runParallel(b, func(ctx context.Context, i int) {
+			_, _ = node.Get(ctx, newRequest(i))
+		})
+	})
+	b.Run("Locked", func(b *testing.B) {
+		runParallel(b, func(ctx context.Context, i int) {
+			locked.get(ctx, newRequest(i))
+		})
+	})
+}
+
+// BenchmarkNodePutWithEviction measures the writes throughput of a node whose local state is full,
+// every write evicting an entry
+func BenchmarkNodePutWithEviction(b *testing.B) {
+	node := benchmarkNode(b)
+	node.delegate.limits = storageLimits{maxEntries: benchmarkKeys, policy: LRU}
+	value := make([]byte, 128)
+	runParallel(b, func(ctx context.Context, i int) {
+		_, _ = node.Put(ctx, connect.NewRequest(&internalpb.PutRequest{Key: "new-" + strconv.Itoa(i), Value: value}))
+	})
+}
+
+// BenchmarkNodePutWithPushPull measures the writes throughput while the local state
+// is continuously serialized for the push/pull and listed
+func BenchmarkNodePutWithPushPull(b *testing.B) {
+	node := benchmarkNode(b)
+	locked := &lockedNode{node: node}
+	value := make([]byte, 128)
+	newRequest := func(i int) *connect.Request[internalpb.PutRequest] {
+		return connect.NewRequest(&internalpb.PutRequest{Key: strconv.Itoa(i % benchmarkKeys), Value: value})
+	}
+
+	b.Run("Sharded", func(b *testing.B) {
+		stop := runInBackground(
+			func() {
+				_ = node.delegate.streamState(defaultStateChunkSize, func(chunk *internalpb.StreamStateResponse) error {
+					_, err := proto.Marshal(chunk)
+					return err
+				})
+			},
+			func() {
+				_, _ = node.List(context.Background(), connect.NewRequest(new(internalpb.ListRequest)))
+			})
+		defer stop()
+
+		runParallel(b, func(ctx context.Context, i int) {
+			_, _ = node.Put(ctx, newRequest(i))
+		})
+	})
+	b.Run("Locked", func(b *testing.B) {
+		stop := runInBackground(locked.pushPull, func() { locked.list(context.Background()) })
+		defer stop()
+
+		runParallel(b, func(ctx context.Context, i int) {
+			locked.put(ctx, newRequest(i))
+		})
+	})
+}
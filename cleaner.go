@@ -24,7 +24,10 @@
 
 package gokv
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 // cleaner runs periodically to remove expired entries
 // from the localState of the given node
@@ -39,7 +42,8 @@ func (cl *cleaner) run(node *Node) {
 	for {
 		select {
 		case <-ticker.C:
-			node.delegate.removeExpired()
+			removed := node.delegate.removeExpired()
+			node.metrics.evictions.Add(context.Background(), int64(removed))
 		case <-cl.stop:
 			ticker.Stop()
 			return
@@ -61,3 +65,19 @@ func runCleaner(c *Node, ci time.Duration) {
 	c.cleaner = j
 	go j.run(c)
 }
+
+// purgeTombstones removes periodically the removed entries whose retention has elapsed.
+// It runs at every tombstone retention whether the cleaner job is enabled or not, hence a tombstone
+// is kept for at most twice the retention
+func (node *Node) purgeTombstones() {
+	ticker := time.NewTicker(node.config.tombstoneRetention)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			node.delegate.removeTombstones()
+		case <-node.stopTombstonesPurge:
+			return
+		}
+	}
+}
@@ -26,10 +26,13 @@ package gokv
 
 import (
 	"context"
+	"errors"
 	nethttp "net/http"
 	"time"
 
 	"connectrpc.com/connect"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/atomic"
 	"google.golang.org/protobuf/proto"
 
@@ -47,20 +50,78 @@ type Client struct {
 	// host defines the host discoveryAddress
 	kvService internalpbconnect.KVServiceClient
 	connected *atomic.Bool
+	// interceptors defines the connect interceptors applied to every call
+	interceptors []connect.Interceptor
+	// meterProvider defines the meter provider used to record the calls metrics
+	meterProvider metric.MeterProvider
+	// tracerProvider defines the tracer provider used to trace the calls
+	tracerProvider trace.TracerProvider
+	tracer         trace.Tracer
+	// callTimeout defines the default timeout of every call attempt
+	callTimeout time.Duration
+	// retryAttempts, retryInitialBackoff and retryMaxBackoff define the calls retry policy
+	retryAttempts       int
+	retryInitialBackoff time.Duration
+	retryMaxBackoff     time.Duration
+	// breakerThreshold and breakerCooldown define the circuit breaker policy
+	breakerThreshold int
+	breakerCooldown  time.Duration
+	// transport defines the http transport settings
+	transport *http.ClientConfig
+	// nearCacheSize and nearCacheTTL define the near cache bounds
+	nearCacheSize int
+	nearCacheTTL  time.Duration
+	// nearCache holds the entries read by the client. It is nil when disabled
+	nearCache *nearCache
+	// stopNearCache stops the near cache invalidation
+	stopNearCache context.CancelFunc
 }
 
-// Put distributes the key/value pair in the cluster
-func (client *Client) Put(ctx context.Context, entry *Entry, expiration time.Duration) error {
+// Put distributes the key/value pair in the cluster. The entry expires after its TTL when set.
+// It returns ErrEntryExpired when a read entry is put back after its expiry deadline without a new TTL
+func (client *Client) Put(ctx context.Context, entry *Entry) (err error) {
 	if !client.connected.Load() {
 		return ErrClientNotConnected
 	}
 
-	_, err := client.kvService.Put(ctx, connect.NewRequest(
+	if entry.expired() {
+		return ErrEntryExpired
+	}
+
+	ctx, span := client.tracer.Start(ctx, "Client.Put", trace.WithAttributes(keyAttribute.String(entry.Key)))
+	defer func() { endSpan(span, err) }()
+
+	_, err = client.kvService.Put(ctx, connect.NewRequest(
 		&internalpb.PutRequest{
 			Key:    entry.Key,
 			Value:  entry.Value,
-			Expiry: setExpiry(expiration),
+			Expiry: setExpiry(entry.TTL),
 		}))
+	if client.nearCache != nil {
+		client.nearCache.invalidate(entry.Key)
+	}
+	return limitError(err)
+}
+
+// limitError maps the node storage limits violations, carried by a LimitViolation error detail, to their typed errors
+func limitError(err error) error {
+	var connectErr *connect.Error
+	if !errors.As(err, &connectErr) {
+		return err
+	}
+
+	for _, detail := range connectErr.Details() {
+		value, detailErr := detail.Value()
+		if detailErr != nil {
+			continue
+		}
+
+		if violation, ok := value.(*internalpb.LimitViolation); ok {
+			if limitErr, ok := limitErrors[violation.GetLimit()]; ok {
+				return limitErr
+			}
+		}
+	}
 	return err
 }
 
@@ -71,14 +132,14 @@ func (client *Client) PutProto(ctx context.Context, key string, value proto.Mess
 		return err
 	}
 
-	entry := &Entry{Key: key, Value: bytea}
-	return client.Put(ctx, entry, expiration)
+	entry := &Entry{Key: key, Value: bytea, TTL: expiration}
+	return client.Put(ctx, entry)
 }
 
 // PutString creates a key/value pair where the value is a string and distributes in the cluster
 func (client *Client) PutString(ctx context.Context, key string, value string, expiration time.Duration) error {
-	entry := &Entry{Key: key, Value: []byte(value)}
-	return client.Put(ctx, entry, expiration)
+	entry := &Entry{Key: key, Value: []byte(value), TTL: expiration}
+	return client.Put(ctx, entry)
 }
 
 // PutAny distributes the key/value pair in the cluster.
@@ -88,8 +149,8 @@ func (client *Client) PutAny(ctx context.Context, key string, value any, expirat
 	if err != nil {
 		return err
 	}
-	entry := &Entry{Key: key, Value: bytea}
-	return client.Put(ctx, entry, expiration)
+	entry := &Entry{Key: key, Value: bytea, TTL: expiration}
+	return client.Put(ctx, entry)
 }
 
 // GetProto retrieves the value of the given from the cluster as protocol buffer message
@@ -123,14 +184,29 @@ func (client *Client) GetAny(ctx context.Context, key string, codec Codec) (any,
 	return codec.Decode(entry.Value)
 }
 
-// Get retrieves the value of the given key from the cluster
-func (client *Client) Get(ctx context.Context, key string) (*Entry, error) {
+// Get retrieves the value of the given key from the cluster.
+// When the near cache is enabled the value is served from it unless the context is set with BypassNearCache
+func (client *Client) Get(ctx context.Context, key string) (_ *Entry, err error) {
 	if !client.connected.Load() {
 		return nil, ErrClientNotConnected
 	}
+
+	var generation uint64
+	cached := client.nearCache != nil && !nearCacheBypassed(ctx)
+	if cached {
+		if entry, ok := client.nearCache.get(key); ok {
+			return entry, nil
+		}
+		generation = client.nearCache.version()
+	}
+
+	ctx, span := client.tracer.Start(ctx, "Client.Get", trace.WithAttributes(keyAttribute.String(key)))
+	defer func() { endSpan(span, err) }()
+
 	response, err := client.kvService.Get(ctx, connect.NewRequest(
 		&internalpb.GetRequest{
-			Key: key,
+			Key:          key,
+			WithMetadata: true,
 		}))
 
 	if err != nil {
@@ -141,47 +217,116 @@ func (client *Client) Get(ctx context.Context, key string) (*Entry, error) {
 		return nil, err
 	}
 
-	return fromNode(response.Msg.GetEntry()), nil
+	entry := fromNode(response.Msg.GetEntry(), response.Msg.GetMetadata())
+	if cached {
+		client.nearCache.set(generation, entry)
+	}
+	return entry, nil
+}
+
+// GetWithMetadata retrieves the value of the given key from the cluster along with the metadata
+// describing the node state it comes from and how fresh it is. The near cache is never used
+func (client *Client) GetWithMetadata(ctx context.Context, key string) (_ *Entry, _ *EntryMetadata, err error) {
+	if !client.connected.Load() {
+		return nil, nil, ErrClientNotConnected
+	}
+
+	ctx, span := client.tracer.Start(ctx, "Client.GetWithMetadata", trace.WithAttributes(keyAttribute.String(key)))
+	defer func() { endSpan(span, err) }()
+
+	response, err := client.kvService.Get(ctx, connect.NewRequest(
+		&internalpb.GetRequest{
+			Key:          key,
+			WithMetadata: true,
+		}))
+
+	if err != nil {
+		code := connect.CodeOf(err)
+		if code == connect.CodeNotFound {
+			return nil, nil, ErrKeyNotFound
+		}
+		return nil, nil, err
+	}
+
+	return fromNode(response.Msg.GetEntry(), response.Msg.GetMetadata()), fromMetadata(response.Msg.GetMetadata()), nil
 }
 
 // List returns the list of entries at a point in time
-func (client *Client) List(ctx context.Context) ([]*Entry, error) {
+func (client *Client) List(ctx context.Context) (_ []*Entry, err error) {
 	if !client.connected.Load() {
 		return nil, ErrClientNotConnected
 	}
 
-	response, err := client.kvService.List(ctx, connect.NewRequest(&internalpb.ListRequest{}))
+	ctx, span := client.tracer.Start(ctx, "Client.List")
+	defer func() { endSpan(span, err) }()
+
+	response, err := client.kvService.List(ctx, connect.NewRequest(&internalpb.ListRequest{WithMetadata: true}))
 	if err != nil {
 		return nil, err
 	}
 
 	entries := make([]*Entry, 0, len(response.Msg.GetEntries()))
 	for _, entry := range response.Msg.GetEntries() {
-		entries = append(entries, fromNode(entry))
+		entries = append(entries, fromNode(entry, response.Msg.GetMetadata()[entry.GetKey()]))
 	}
 	return entries, nil
 }
 
+// ListWithMetadata returns the list of entries at a point in time along with the metadata of every entry by key
+// describing the node state it comes from and how fresh it is
+func (client *Client) ListWithMetadata(ctx context.Context) (_ []*Entry, _ map[string]*EntryMetadata, err error) {
+	if !client.connected.Load() {
+		return nil, nil, ErrClientNotConnected
+	}
+
+	ctx, span := client.tracer.Start(ctx, "Client.ListWithMetadata")
+	defer func() { endSpan(span, err) }()
+
+	response, err := client.kvService.List(ctx, connect.NewRequest(&internalpb.ListRequest{WithMetadata: true}))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	entries := make([]*Entry, 0, len(response.Msg.GetEntries()))
+	metadata := make(map[string]*EntryMetadata, len(response.Msg.GetMetadata()))
+	for _, entry := range response.Msg.GetEntries() {
+		entryMetadata := response.Msg.GetMetadata()[entry.GetKey()]
+		entries = append(entries, fromNode(entry, entryMetadata))
+		metadata[entry.GetKey()] = fromMetadata(entryMetadata)
+	}
+	return entries, metadata, nil
+}
+
 // Delete deletes a given key from the cluster
 // nolint
-func (client *Client) Delete(ctx context.Context, key string) error {
+func (client *Client) Delete(ctx context.Context, key string) (err error) {
 	if !client.connected.Load() {
 		return ErrClientNotConnected
 	}
-	_, err := client.kvService.Delete(ctx, connect.NewRequest(
+
+	ctx, span := client.tracer.Start(ctx, "Client.Delete", trace.WithAttributes(keyAttribute.String(key)))
+	defer func() { endSpan(span, err) }()
+
+	_, err = client.kvService.Delete(ctx, connect.NewRequest(
 		&internalpb.DeleteRequest{
 			Key: key,
 		}))
 
-	return err
+	if client.nearCache != nil {
+		client.nearCache.invalidate(key)
+	}
+	return limitError(err)
 }
 
 // Exists checks the existence of a given key in the cluster
-func (client *Client) Exists(ctx context.Context, key string) (bool, error) {
+func (client *Client) Exists(ctx context.Context, key string) (_ bool, err error) {
 	if !client.connected.Load() {
 		return false, ErrClientNotConnected
 	}
 
+	ctx, span := client.tracer.Start(ctx, "Client.Exists", trace.WithAttributes(keyAttribute.String(key)))
+	defer func() { endSpan(span, err) }()
+
 	response, err := client.kvService.KeyExists(ctx, connect.NewRequest(
 		&internalpb.KeyExistsRequest{
 			Key: key,
@@ -194,6 +339,119 @@ func (client *Client) Exists(ctx context.Context, key string) (bool, error) {
 	return response.Msg.GetExists(), nil
 }
 
+// ClusterInfo returns the cluster members as seen by the node the client is connected to
+func (client *Client) ClusterInfo(ctx context.Context) (_ *ClusterInfo, err error) {
+	if !client.connected.Load() {
+		return nil, ErrClientNotConnected
+	}
+
+	ctx, span := client.tracer.Start(ctx, "Client.ClusterInfo")
+	defer func() { endSpan(span, err) }()
+
+	response, err := client.kvService.ClusterInfo(ctx, connect.NewRequest(&internalpb.ClusterInfoRequest{}))
+	if err != nil {
+		return nil, err
+	}
+
+	return clusterInfoFromProto(response.Msg), nil
+}
+
+// Watch streams the changes of the given keys, or of all the keys when none is given, as seen by
+// the node the client is connected to. The handler is called for every change until the context is canceled,
+// the handler returns an error or the node ends the stream. It returns nil when the context is canceled
+func (client *Client) Watch(ctx context.Context, handler func(change *KeyChange) error, keys ...string) error {
+	return client.watch(ctx, keys, nil, handler)
+}
+
+// WatchWithReady streams the changes of the given keys as Watch does and calls onReady once the stream is established,
+// before the handler is called for any change. Reading the keys in onReady does not miss the changes made meanwhile.
+// The stream is ended when onReady returns an error
+func (client *Client) WatchWithReady(ctx context.Context, onReady func() error, handler func(change *KeyChange) error, keys ...string) error {
+	return client.watch(ctx, keys, onReady, handler)
+}
+
+// watch streams the changes of the given keys and calls onReady once the stream is established
+func (client *Client) watch(ctx context.Context, keys []string, onReady func() error, handler func(change *KeyChange) error) error {
+	if !client.connected.Load() {
+		return ErrClientNotConnected
+	}
+
+	// the stream context is canceled before closing so that returning early does not wait for the node to end the stream
+	streamCtx, cancel := context.WithCancel(ctx)
+	stream, err := client.kvService.Watch(streamCtx, connect.NewRequest(&internalpb.WatchRequest{Keys: keys}))
+	if err != nil {
+		cancel()
+		if ctx.Err() != nil {
+			return nil
+		}
+		return err
+	}
+	defer stream.Close()
+	defer cancel()
+
+	for stream.Receive() {
+		response := stream.Msg()
+		switch response.GetType() {
+		case internalpb.WatchEventType_WATCH_EVENT_TYPE_READY:
+			if onReady != nil {
+				err = onReady()
+			}
+		case internalpb.WatchEventType_WATCH_EVENT_TYPE_PUT:
+			err = handler(&KeyChange{Key: response.GetKey(), Entry: fromNode(response.GetEntry(), nil)})
+		case internalpb.WatchEventType_WATCH_EVENT_TYPE_DELETE:
+			err = handler(&KeyChange{Key: response.GetKey(), Deleted: true})
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if ctx.Err() != nil {
+		return nil
+	}
+
+	if err := stream.Err(); err != nil {
+		var connectErr *connect.Error
+		if errors.As(err, &connectErr) && connectErr.Code() == connect.CodeResourceExhausted && connectErr.Message() == ErrWatchLagging.Error() {
+			return ErrWatchLagging
+		}
+		return err
+	}
+	return ErrWatchClosed
+}
+
+// NearCacheStats returns the near cache statistics. The statistics are empty when the near cache is disabled
+func (client *Client) NearCacheStats() NearCacheStats {
+	if client.nearCache == nil {
+		return NearCacheStats{}
+	}
+	return client.nearCache.stats()
+}
+
+// invalidateNearCache watches the node and removes the changed keys from the near cache.
+// The near cache is emptied and disabled whenever the stream ends since it may have missed changes
+func (client *Client) invalidateNearCache(ctx context.Context) {
+	for {
+		_ = client.watch(ctx, nil,
+			func() error {
+				client.nearCache.reset(true)
+				return nil
+			},
+			func(change *KeyChange) error {
+				client.nearCache.invalidate(change.Key)
+				return nil
+			})
+		client.nearCache.reset(false)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(nearCacheRetryInterval):
+		}
+	}
+}
+
 // Close closes the client connection to the cluster
 func (client *Client) Close() error {
 	// no-op when the client is not connected
@@ -201,22 +459,61 @@ func (client *Client) Close() error {
 		return nil
 	}
 	client.connected.Store(false)
+	if client.stopNearCache != nil {
+		client.stopNearCache()
+	}
 	client.httpClient.CloseIdleConnections()
 	return nil
 }
 
 // NewClient creates an instance of the cluster Client
 // host and port are a Go-KV cluster node host and port
-func NewClient(host string, port int) *Client {
-	httpClient := http.NewClient()
-	kvService := internalpbconnect.NewKVServiceClient(
-		httpClient,
+func NewClient(host string, port int, opts ...ClientOption) *Client {
+	client := &Client{
+		connected: atomic.NewBool(true),
+		transport: http.DefaultClientConfig(),
+	}
+
+	// apply the various options
+	for _, opt := range opts {
+		opt.Apply(client)
+	}
+
+	client.httpClient = http.NewClient(client.transport)
+
+	interceptors := client.interceptors
+	// the interceptor creation only fails when the instruments cannot be created
+	// in that case the client simply does not record its calls telemetry
+	if interceptor, err := newTelemetryInterceptor(client.meterProvider, client.tracerProvider); err == nil && interceptor != nil {
+		interceptors = append([]connect.Interceptor{interceptor}, interceptors...)
+	}
+
+	// the retries wrap the circuit breaker which wraps every call attempt timeout
+	if client.retryAttempts > 1 {
+		interceptors = append(interceptors, newRetryInterceptor(client.retryAttempts, client.retryInitialBackoff, client.retryMaxBackoff))
+	}
+
+	if client.breakerThreshold > 0 {
+		interceptors = append(interceptors, newCircuitBreaker(client.breakerThreshold, client.breakerCooldown).interceptor())
+	}
+
+	if client.callTimeout > 0 {
+		interceptors = append(interceptors, newTimeoutInterceptor(client.callTimeout))
+	}
+
+	client.tracer = newTracer(client.tracerProvider)
+
+	client.kvService = internalpbconnect.NewKVServiceClient(
+		client.httpClient,
 		http.URL(host, port),
-		// TODO: add observability options
+		connect.WithInterceptors(interceptors...),
 	)
-	return &Client{
-		httpClient: httpClient,
-		kvService:  kvService,
-		connected:  atomic.NewBool(true),
+
+	if client.nearCacheSize > 0 {
+		var ctx context.Context
+		ctx, client.stopNearCache = context.WithCancel(context.Background())
+		client.nearCache = newNearCache(client.nearCacheSize, client.nearCacheTTL)
+		go client.invalidateNearCache(ctx)
 	}
+	return client
 }
@@ -0,0 +1,146 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2024 Arsene Tochemey Gandote
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package gokv
+
+import (
+	"time"
+
+	"connectrpc.com/connect"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ClientOption is the interface that applies a configuration option to the Client.
+type ClientOption interface {
+	// Apply sets the ClientOption value of a Client.
+	Apply(client *Client)
+}
+
+var _ ClientOption = ClientOptionFunc(nil)
+
+// ClientOptionFunc implements the ClientOption interface.
+type ClientOptionFunc func(client *Client)
+
+// Apply applies the Client's option
+func (f ClientOptionFunc) Apply(client *Client) {
+	f(client)
+}
+
+// WithClientInterceptors sets the connect interceptors to apply to every call
+// made by the Client. This can be used to add authentication, tracing, retries
+// or logging to the client calls.
+func WithClientInterceptors(interceptors ...connect.Interceptor) ClientOption {
+	return ClientOptionFunc(func(client *Client) {
+		client.interceptors = append(client.interceptors, interceptors...)
+	})
+}
+
+// WithClientMetrics sets the meter provider used to record the Client calls
+// metrics such as the calls count and latencies.
+func WithClientMetrics(provider metric.MeterProvider) ClientOption {
+	return ClientOptionFunc(func(client *Client) {
+		client.meterProvider = provider
+	})
+}
+
+// WithClientTracing sets the tracer provider used to trace the Client calls.
+// The trace context is propagated to the node serving the calls.
+func WithClientTracing(provider trace.TracerProvider) ClientOption {
+	return ClientOptionFunc(func(client *Client) {
+		client.tracerProvider = provider
+	})
+}
+
+// WithCallTimeout sets the default timeout of every Client call attempt.
+// The caller deadline is kept when it is earlier.
+func WithCallTimeout(timeout time.Duration) ClientOption {
+	return ClientOptionFunc(func(client *Client) {
+		client.callTimeout = timeout
+	})
+}
+
+// WithRetry retries the Client calls failing with an Unavailable or a DeadlineExceeded error.
+// maxAttempts is the maximum number of attempts including the first call. The delay between two attempts
+// grows exponentially with jitter from initialBackoff up to maxBackoff.
+func WithRetry(maxAttempts int, initialBackoff, maxBackoff time.Duration) ClientOption {
+	return ClientOptionFunc(func(client *Client) {
+		client.retryAttempts = maxAttempts
+		client.retryInitialBackoff = initialBackoff
+		client.retryMaxBackoff = maxBackoff
+	})
+}
+
+// WithCircuitBreaker sets a circuit breaker per endpoint, that is per called host and procedure. After failureThreshold consecutive
+// failures the calls to the endpoint are rejected with ErrCircuitOpen until the cooldown elapses.
+// Then a single trial call is let through to close the circuit when it succeeds.
+func WithCircuitBreaker(failureThreshold int, cooldown time.Duration) ClientOption {
+	return ClientOptionFunc(func(client *Client) {
+		client.breakerThreshold = failureThreshold
+		client.breakerCooldown = cooldown
+	})
+}
+
+// WithPingTimeout sets the timeout after which a connection is closed when
+// a health check ping is not answered. Defaults to 30 seconds
+func WithPingTimeout(timeout time.Duration) ClientOption {
+	return ClientOptionFunc(func(client *Client) {
+		if timeout > 0 {
+			client.transport.PingTimeout = timeout
+		}
+	})
+}
+
+// WithReadIdleTimeout sets the timeout after which a health check ping is sent when
+// no frame has been received on a connection. Defaults to 30 seconds
+func WithReadIdleTimeout(timeout time.Duration) ClientOption {
+	return ClientOptionFunc(func(client *Client) {
+		if timeout > 0 {
+			client.transport.ReadIdleTimeout = timeout
+		}
+	})
+}
+
+// WithIdleConnTimeout sets the maximum amount of time an idle connection remains open.
+// Defaults to no limit
+func WithIdleConnTimeout(timeout time.Duration) ClientOption {
+	return ClientOptionFunc(func(client *Client) {
+		if timeout > 0 {
+			client.transport.IdleConnTimeout = timeout
+		}
+	})
+}
+
+// WithNearCache enables an in-process cache of the entries read by the Client holding at most maxEntries entries.
+// The entries are cached for at most the given ttl, zero meaning until they change or expire.
+// The cache is kept up to date by a stream of the changes seen by the node and is only used while that stream is established.
+// One can bypass the cache for a given call with BypassNearCache
+func WithNearCache(maxEntries int, ttl time.Duration) ClientOption {
+	return ClientOptionFunc(func(client *Client) {
+		if maxEntries > 0 {
+			client.nearCacheSize = maxEntries
+			client.nearCacheTTL = ttl
+		}
+	})
+}
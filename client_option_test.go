@@ -0,0 +1,89 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2024 Arsene Tochemey Gandote
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package gokv
+
+import (
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/metric/noop"
+	tracenoop "go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/tochemey/gokv/internal/http"
+)
+
+func TestClientOptions(t *testing.T) {
+	interceptor := connect.UnaryInterceptorFunc(func(next connect.UnaryFunc) connect.UnaryFunc {
+		return next
+	})
+
+	testCases := []struct {
+		name     string
+		option   ClientOption
+		expected int
+	}{
+		{
+			name:     "WithClientInterceptors",
+			option:   WithClientInterceptors(interceptor),
+			expected: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var client Client
+			tc.option.Apply(&client)
+			assert.Len(t, client.interceptors, tc.expected)
+		})
+	}
+
+	t.Run("WithClientMetrics", func(t *testing.T) {
+		provider := noop.NewMeterProvider()
+		var client Client
+		WithClientMetrics(provider).Apply(&client)
+		assert.Equal(t, provider, client.meterProvider)
+	})
+
+	t.Run("WithClientTracing", func(t *testing.T) {
+		provider := tracenoop.NewTracerProvider()
+		var client Client
+		WithClientTracing(provider).Apply(&client)
+		assert.Equal(t, provider, client.tracerProvider)
+	})
+
+	t.Run("WithTransportTimeouts", func(t *testing.T) {
+		client := Client{transport: http.DefaultClientConfig()}
+		WithPingTimeout(time.Second).Apply(&client)
+		WithReadIdleTimeout(2 * time.Second).Apply(&client)
+		WithIdleConnTimeout(3 * time.Second).Apply(&client)
+		// invalid timeouts are ignored
+		WithPingTimeout(-1).Apply(&client)
+		assert.Equal(t, time.Second, client.transport.PingTimeout)
+		assert.Equal(t, 2*time.Second, client.transport.ReadIdleTimeout)
+		assert.Equal(t, 3*time.Second, client.transport.IdleConnTimeout)
+	})
+}
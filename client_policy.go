@@ -0,0 +1,205 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2024 Arsene Tochemey Gandote
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package gokv
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/flowchartsman/retry"
+)
+
+// newTimeoutInterceptor creates an interceptor bounding every unary call to the given timeout.
+// The caller deadline is kept when it is earlier.
+func newTimeoutInterceptor(timeout time.Duration) connect.Interceptor {
+	return connect.UnaryInterceptorFunc(func(next connect.UnaryFunc) connect.UnaryFunc {
+		return func(ctx context.Context, request connect.AnyRequest) (connect.AnyResponse, error) {
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			return next(ctx, request)
+		}
+	})
+}
+
+// newRetryInterceptor creates an interceptor retrying the unary calls failing with
+// an Unavailable or a DeadlineExceeded error with an exponential backoff and jitter
+func newRetryInterceptor(maxAttempts int, initialBackoff, maxBackoff time.Duration) connect.Interceptor {
+	retrier := retry.NewRetrier(maxAttempts, initialBackoff, maxBackoff)
+	return connect.UnaryInterceptorFunc(func(next connect.UnaryFunc) connect.UnaryFunc {
+		return func(ctx context.Context, request connect.AnyRequest) (connect.AnyResponse, error) {
+			var (
+				response connect.AnyResponse
+				lastErr  error
+			)
+
+			// the last error is returned as is since the retrier does not unwrap a stopping error returned by the last attempt
+			if err := retrier.RunContext(ctx, func(ctx context.Context) error {
+				if response, lastErr = next(ctx, request); lastErr != nil && !retryable(ctx, lastErr) {
+					return retry.Stop(lastErr)
+				}
+				return lastErr
+			}); err != nil {
+				return nil, lastErr
+			}
+			return response, nil
+		}
+	})
+}
+
+// retryable returns true when the failed call can be retried
+func retryable(ctx context.Context, err error) bool {
+	// the caller gave up or the circuit is open
+	if ctx.Err() != nil || errors.Is(err, ErrCircuitOpen) {
+		return false
+	}
+
+	code := connect.CodeOf(err)
+	return code == connect.CodeUnavailable || code == connect.CodeDeadlineExceeded
+}
+
+// circuitState defines the state of a circuit
+type circuitState int
+
+const (
+	// circuitClosed lets the calls through
+	circuitClosed circuitState = iota
+	// circuitOpen rejects the calls
+	circuitOpen
+	// circuitHalfOpen lets a single trial call through
+	circuitHalfOpen
+)
+
+// circuit holds the state of an endpoint circuit
+type circuit struct {
+	state    circuitState
+	failures int
+	openedAt time.Time
+}
+
+// circuitBreaker rejects the calls to an endpoint after a number of consecutive failures.
+// After the cooldown a single trial call is let through: the circuit closes when it succeeds
+// and opens again when it fails.
+type circuitBreaker struct {
+	mu        *sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	circuits  map[string]*circuit
+}
+
+// newCircuitBreaker creates an instance of circuitBreaker
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{
+		mu:        new(sync.Mutex),
+		threshold: threshold,
+		cooldown:  cooldown,
+		circuits:  make(map[string]*circuit),
+	}
+}
+
+// interceptor returns the circuit breaker interceptor.
+// The endpoints are the called procedures of the called hosts, so that a failing host
+// does not open the circuits of the other hosts sharing the interceptor
+func (breaker *circuitBreaker) interceptor() connect.Interceptor {
+	return connect.UnaryInterceptorFunc(func(next connect.UnaryFunc) connect.UnaryFunc {
+		return func(ctx context.Context, request connect.AnyRequest) (connect.AnyResponse, error) {
+			endpoint := request.Peer().Addr + request.Spec().Procedure
+			if err := breaker.allow(endpoint); err != nil {
+				return nil, err
+			}
+
+			response, err := next(ctx, request)
+			breaker.record(endpoint, err)
+			return response, err
+		}
+	})
+}
+
+// allow returns an error when the given endpoint circuit rejects the call
+func (breaker *circuitBreaker) allow(endpoint string) error {
+	breaker.mu.Lock()
+	defer breaker.mu.Unlock()
+
+	current, ok := breaker.circuits[endpoint]
+	if !ok {
+		return nil
+	}
+
+	switch current.state {
+	case circuitOpen:
+		if time.Since(current.openedAt) < breaker.cooldown {
+			return connect.NewError(connect.CodeUnavailable, ErrCircuitOpen)
+		}
+		// let a trial call through
+		current.state = circuitHalfOpen
+		return nil
+	case circuitHalfOpen:
+		// a trial call is in flight
+		return connect.NewError(connect.CodeUnavailable, ErrCircuitOpen)
+	default:
+		return nil
+	}
+}
+
+// record records the outcome of a call to the given endpoint
+func (breaker *circuitBreaker) record(endpoint string, err error) {
+	breaker.mu.Lock()
+	defer breaker.mu.Unlock()
+
+	current, ok := breaker.circuits[endpoint]
+	if !ok {
+		current = new(circuit)
+		breaker.circuits[endpoint] = current
+	}
+
+	if !endpointFailure(err) {
+		current.state = circuitClosed
+		current.failures = 0
+		return
+	}
+
+	current.failures++
+	if current.state == circuitHalfOpen || current.failures >= breaker.threshold {
+		current.state = circuitOpen
+		current.openedAt = time.Now()
+	}
+}
+
+// endpointFailure returns true when the given error is due to the endpoint failure.
+// Business errors, for instance a key not found, do not count as failures
+func endpointFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	switch connect.CodeOf(err) {
+	case connect.CodeUnavailable, connect.CodeDeadlineExceeded, connect.CodeInternal:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,219 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2024 Arsene Tochemey Gandote
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package gokv
+
+import (
+	"context"
+	"errors"
+	"net"
+	nethttp "net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+
+	"github.com/tochemey/gokv/internal/internalpb"
+	"github.com/tochemey/gokv/internal/internalpb/internalpbconnect"
+)
+
+// flakyService is a KVService failing its first calls
+type flakyService struct {
+	internalpbconnect.UnimplementedKVServiceHandler
+	calls    *atomic.Int32
+	failures int32
+	code     connect.Code
+	delay    time.Duration
+}
+
+func (s *flakyService) Get(ctx context.Context, request *connect.Request[internalpb.GetRequest]) (*connect.Response[internalpb.GetResponse], error) {
+	if s.calls.Inc() <= s.failures {
+		return nil, connect.NewError(s.code, errors.New("failure"))
+	}
+
+	select {
+	case <-time.After(s.delay):
+	case <-ctx.Done():
+		return nil, connect.NewError(connect.CodeDeadlineExceeded, ctx.Err())
+	}
+
+	if request.Msg.GetKey() == "missing" {
+		return nil, connect.NewError(connect.CodeNotFound, ErrKeyNotFound)
+	}
+	return connect.NewResponse(&internalpb.GetResponse{
+		Entry: &internalpb.Entry{Key: request.Msg.GetKey(), Value: []byte("value")},
+	}), nil
+}
+
+// startFlakyService starts the given service and returns a client connected to it
+func startFlakyService(t *testing.T, service *flakyService, opts ...ClientOption) *Client {
+	t.Helper()
+	mux := nethttp.NewServeMux()
+	mux.Handle(internalpbconnect.NewKVServiceHandler(service))
+	server := httptest.NewServer(h2c.NewHandler(mux, new(http2.Server)))
+	t.Cleanup(server.Close)
+
+	host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	require.NoError(t, err)
+	portNum, err := strconv.Atoi(port)
+	require.NoError(t, err)
+
+	client := NewClient(host, portNum, opts...)
+	t.Cleanup(func() { _ = client.Close() })
+	return client
+}
+
+func TestClientPolicies(t *testing.T) {
+	ctx := context.Background()
+	t.Run("With retry", func(t *testing.T) {
+		service := &flakyService{calls: atomic.NewInt32(0), failures: 2, code: connect.CodeUnavailable}
+		client := startFlakyService(t, service, WithRetry(3, 10*time.Millisecond, 50*time.Millisecond))
+
+		value, err := client.GetString(ctx, "key")
+		require.NoError(t, err)
+		assert.Equal(t, "value", value)
+		assert.EqualValues(t, 3, service.calls.Load())
+	})
+	t.Run("With retry exhausted", func(t *testing.T) {
+		service := &flakyService{calls: atomic.NewInt32(0), failures: 5, code: connect.CodeUnavailable}
+		client := startFlakyService(t, service, WithRetry(3, 10*time.Millisecond, 50*time.Millisecond))
+
+		_, err := client.Get(ctx, "key")
+		require.Error(t, err)
+		assert.Equal(t, connect.CodeUnavailable, connect.CodeOf(err))
+		assert.EqualValues(t, 3, service.calls.Load())
+	})
+	t.Run("With non retryable error", func(t *testing.T) {
+		service := &flakyService{calls: atomic.NewInt32(0), failures: 5, code: connect.CodeInternal}
+		client := startFlakyService(t, service, WithRetry(3, 10*time.Millisecond, 50*time.Millisecond))
+
+		_, err := client.Get(ctx, "key")
+		require.Error(t, err)
+		assert.EqualValues(t, 1, service.calls.Load())
+
+		_, err = client.Get(ctx, "missing")
+		require.Error(t, err)
+	})
+	t.Run("With non retryable error on the last attempt", func(t *testing.T) {
+		service := &flakyService{calls: atomic.NewInt32(0), failures: 1, code: connect.CodeUnavailable}
+		client := startFlakyService(t, service, WithRetry(2, 10*time.Millisecond, 50*time.Millisecond))
+
+		_, err := client.Get(ctx, "missing")
+		require.ErrorIs(t, err, ErrKeyNotFound)
+		assert.EqualValues(t, 2, service.calls.Load())
+	})
+	t.Run("With call timeout", func(t *testing.T) {
+		service := &flakyService{calls: atomic.NewInt32(0), delay: time.Second}
+		client := startFlakyService(t, service, WithCallTimeout(100*time.Millisecond))
+
+		start := time.Now()
+		_, err := client.Get(ctx, "key")
+		require.Error(t, err)
+		assert.Equal(t, connect.CodeDeadlineExceeded, connect.CodeOf(err))
+		assert.Less(t, time.Since(start), time.Second)
+	})
+	t.Run("With call timeout retried", func(t *testing.T) {
+		service := &flakyService{calls: atomic.NewInt32(0), delay: 200 * time.Millisecond}
+		client := startFlakyService(t, service,
+			WithCallTimeout(100*time.Millisecond),
+			WithRetry(2, 10*time.Millisecond, 50*time.Millisecond))
+
+		_, err := client.Get(ctx, "key")
+		require.Error(t, err)
+		assert.Equal(t, connect.CodeDeadlineExceeded, connect.CodeOf(err))
+		assert.EqualValues(t, 2, service.calls.Load())
+	})
+	t.Run("With circuit breaker", func(t *testing.T) {
+		service := &flakyService{calls: atomic.NewInt32(0), failures: 2, code: connect.CodeUnavailable}
+		client := startFlakyService(t, service, WithCircuitBreaker(2, 200*time.Millisecond))
+
+		for i := 0; i < 2; i++ {
+			_, err := client.Get(ctx, "key")
+			require.Error(t, err)
+		}
+
+		// the circuit is open
+		_, err := client.Get(ctx, "key")
+		require.ErrorIs(t, err, ErrCircuitOpen)
+		assert.EqualValues(t, 2, service.calls.Load())
+
+		// the trial call closes the circuit after the cooldown
+		time.Sleep(250 * time.Millisecond)
+		value, err := client.GetString(ctx, "key")
+		require.NoError(t, err)
+		assert.Equal(t, "value", value)
+
+		// a key not found does not count as a failure
+		for i := 0; i < 3; i++ {
+			_, err = client.Get(ctx, "missing")
+			require.ErrorIs(t, err, ErrKeyNotFound)
+		}
+		_, err = client.Get(ctx, "key")
+		require.NoError(t, err)
+	})
+	t.Run("With circuit breaker shared by some hosts", func(t *testing.T) {
+		breaker := newCircuitBreaker(1, time.Minute)
+		failing := &flakyService{calls: atomic.NewInt32(0), failures: 5, code: connect.CodeUnavailable}
+		healthy := &flakyService{calls: atomic.NewInt32(0)}
+		failingClient := startFlakyService(t, failing, WithClientInterceptors(breaker.interceptor()))
+		healthyClient := startFlakyService(t, healthy, WithClientInterceptors(breaker.interceptor()))
+
+		_, err := failingClient.Get(ctx, "key")
+		require.Error(t, err)
+		_, err = failingClient.Get(ctx, "key")
+		require.ErrorIs(t, err, ErrCircuitOpen)
+
+		// the circuit of the same procedure on the other host is still closed
+		value, err := healthyClient.GetString(ctx, "key")
+		require.NoError(t, err)
+		assert.Equal(t, "value", value)
+		assert.EqualValues(t, 1, failing.calls.Load())
+	})
+	t.Run("With circuit breaker reopened", func(t *testing.T) {
+		breaker := newCircuitBreaker(1, 50*time.Millisecond)
+		failure := connect.NewError(connect.CodeUnavailable, errors.New("failure"))
+
+		breaker.record("endpoint", failure)
+		require.ErrorIs(t, breaker.allow("endpoint"), ErrCircuitOpen)
+
+		time.Sleep(60 * time.Millisecond)
+		require.NoError(t, breaker.allow("endpoint"))
+		// only one trial call is let through
+		require.ErrorIs(t, breaker.allow("endpoint"), ErrCircuitOpen)
+
+		// the failed trial opens the circuit again
+		breaker.record("endpoint", failure)
+		require.ErrorIs(t, breaker.allow("endpoint"), ErrCircuitOpen)
+
+		// the other endpoints are not affected
+		require.NoError(t, breaker.allow("other"))
+	})
+}
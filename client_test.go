@@ -27,11 +27,14 @@ package gokv
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"testing"
 	"time"
 
+	"connectrpc.com/connect"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
 	"google.golang.org/protobuf/proto"
 
 	"github.com/tochemey/gokv/internal/lib"
@@ -187,9 +190,10 @@ func TestClient(t *testing.T) {
 		entry := &Entry{
 			Key:   key,
 			Value: bytea,
+			TTL:   expiration,
 		}
 
-		err = node2.Client().Put(ctx, entry, expiration)
+		err = node2.Client().Put(ctx, entry)
 		require.NoError(t, err)
 
 		// wait for the key to be distributed in the cluster
@@ -214,6 +218,322 @@ func TestClient(t *testing.T) {
 			srv.Shutdown()
 		})
 	})
+	t.Run("With GetWithMetadata", func(t *testing.T) {
+		ctx := context.Background()
+		// start the NATS server
+		srv := startNatsServer(t)
+		// create a cluster node1
+		node1, sd1 := startNode(t, srv.Addr().String())
+		require.NotNil(t, node1)
+		// create a cluster node2
+		node2, sd2 := startNode(t, srv.Addr().String())
+		require.NotNil(t, node2)
+
+		key := "my-key"
+		expiration := time.Minute
+		err := node2.Client().PutString(ctx, key, "my-value", expiration)
+		require.NoError(t, err)
+
+		// the entry carries its timestamps, its origin and the version of the origin state
+		entry, err := node2.Client().Get(ctx, key)
+		require.NoError(t, err)
+		assert.Equal(t, node2.delegate.self, entry.Origin)
+		assert.NotZero(t, entry.Version)
+		assert.False(t, entry.LastUpdated.IsZero())
+		assert.WithinDuration(t, entry.LastUpdated.Add(expiration), entry.ExpiresAt, time.Millisecond)
+		assert.Positive(t, entry.TTL)
+
+		// the writing node serves the entry from its own state
+		var metadata *EntryMetadata
+		entry, metadata, err = node2.Client().GetWithMetadata(ctx, key)
+		require.NoError(t, err)
+		assert.Equal(t, []byte("my-value"), entry.Value)
+		assert.Equal(t, node2.delegate.self, entry.Origin)
+		assert.Equal(t, metadata.Version, entry.Version)
+		assert.Equal(t, node2.delegate.self, metadata.Source)
+		assert.Equal(t, node2.delegate.self, metadata.ServedBy)
+		assert.NotZero(t, metadata.Version)
+		assert.False(t, metadata.LastUpdated.IsZero())
+		assert.Positive(t, metadata.TTL)
+		assert.LessOrEqual(t, metadata.TTL, expiration)
+		assert.Zero(t, metadata.SinceLastSync)
+
+		// the other node serves the entry from the merged state of the writing node
+		require.Eventually(t, func() bool {
+			entry, metadata, err = node1.Client().GetWithMetadata(ctx, key)
+			return err == nil
+		}, 5*time.Second, 100*time.Millisecond)
+		assert.Equal(t, []byte("my-value"), entry.Value)
+		assert.Equal(t, node2.delegate.self, metadata.Source)
+		assert.Equal(t, node1.delegate.self, metadata.ServedBy)
+		assert.NotZero(t, metadata.Version)
+		assert.Positive(t, metadata.TTL)
+		assert.Positive(t, metadata.SinceLastSync)
+
+		entries, err := node1.Client().List(ctx)
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.Equal(t, node2.delegate.self, entries[0].Origin)
+		assert.NotZero(t, entries[0].Version)
+		assert.Equal(t, entry.ExpiresAt, entries[0].ExpiresAt)
+
+		var entriesMetadata map[string]*EntryMetadata
+		entries, entriesMetadata, err = node1.Client().ListWithMetadata(ctx)
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.Equal(t, node2.delegate.self, entries[0].Origin)
+		assert.Equal(t, metadata.Version, entries[0].Version)
+		assert.Equal(t, entry.ExpiresAt, entries[0].ExpiresAt)
+		require.Contains(t, entriesMetadata, key)
+		assert.Equal(t, node2.delegate.self, entriesMetadata[key].Source)
+		assert.Equal(t, node1.delegate.self, entriesMetadata[key].ServedBy)
+
+		// a read entry can be put back as it is
+		entries[0].Value = []byte("other-value")
+		require.NoError(t, node1.Client().Put(ctx, entries[0]))
+		entry, _, err = node1.Client().GetWithMetadata(ctx, key)
+		require.NoError(t, err)
+		assert.Equal(t, node1.delegate.self, entry.Origin)
+		assert.Positive(t, entry.TTL)
+		assert.LessOrEqual(t, entry.TTL, expiration)
+
+		// a read entry that has expired is not put back without expiration
+		entry.ExpiresAt = time.Now().Add(-time.Millisecond)
+		entry.TTL = max(time.Until(entry.ExpiresAt), 0)
+		assert.ErrorIs(t, node1.Client().Put(ctx, entry), ErrEntryExpired)
+		// unless a new TTL is set
+		entry.TTL = time.Minute
+		require.NoError(t, node1.Client().Put(ctx, entry))
+
+		_, _, err = node1.Client().GetWithMetadata(ctx, "unknown")
+		assert.ErrorIs(t, err, ErrKeyNotFound)
+
+		assert.NoError(t, node1.Stop(ctx))
+		assert.NoError(t, node2.Stop(ctx))
+		assert.NoError(t, sd1.Close())
+		assert.NoError(t, sd2.Close())
+		srv.Shutdown()
+	})
+	t.Run("With interceptors", func(t *testing.T) {
+		ctx := context.Background()
+		// start the NATS server
+		srv := startNatsServer(t)
+
+		// define the node and client interceptors
+		serverCalls := atomic.NewInt32(0)
+		clientCalls := atomic.NewInt32(0)
+		serverInterceptor := countingInterceptor(serverCalls)
+		clientInterceptor := countingInterceptor(clientCalls)
+
+		// create a cluster node1 with the server interceptor
+		node1, sd1 := startNode(t, srv.Addr().String(), func(config *Config) {
+			config.WithInterceptors(serverInterceptor)
+		})
+		require.NotNil(t, node1)
+
+		// create a client with the client interceptor
+		client := NewClient(node1.config.host, int(node1.config.port), WithClientInterceptors(clientInterceptor))
+
+		key := "my-key"
+		value := "my-value"
+		require.NoError(t, client.PutString(ctx, key, value, NoExpiration))
+
+		actual, err := client.GetString(ctx, key)
+		require.NoError(t, err)
+		require.Equal(t, value, actual)
+
+		assert.EqualValues(t, 2, clientCalls.Load())
+		assert.EqualValues(t, 2, serverCalls.Load())
+
+		t.Cleanup(func() {
+			assert.NoError(t, client.Close())
+			assert.NoError(t, node1.Stop(ctx))
+			assert.NoError(t, sd1.Close())
+			srv.Shutdown()
+		})
+	})
+
+	t.Run("With Watch", func(t *testing.T) {
+		ctx := context.Background()
+		// start the NATS server
+		srv := startNatsServer(t)
+		node1, sd1 := startNode(t, srv.Addr().String())
+		node2, sd2 := startNode(t, srv.Addr().String())
+
+		changes := make(chan *KeyChange, 10)
+		watchCtx, cancel := context.WithCancel(ctx)
+		done := make(chan error, 1)
+		go func() {
+			done <- node1.Client().Watch(watchCtx, func(change *KeyChange) error {
+				changes <- change
+				return nil
+			}, "key1", "key2")
+		}()
+
+		next := func() *KeyChange {
+			select {
+			case change := <-changes:
+				return change
+			case <-time.After(5 * time.Second):
+				t.Fatal("change not received")
+				return nil
+			}
+		}
+
+		// the watch stream outlives the node write timeout
+		lib.Pause(2 * time.Second)
+		select {
+		case err := <-done:
+			t.Fatalf("watch stream ended: %v", err)
+		default:
+		}
+		// a local change
+		require.NoError(t, node1.Client().PutString(ctx, "key1", "value1", NoExpiration))
+		change := next()
+		assert.Equal(t, "key1", change.Key)
+		assert.False(t, change.Deleted)
+		assert.Equal(t, []byte("value1"), change.Entry.Value)
+
+		// the keys not watched are filtered out
+		require.NoError(t, node2.Client().PutString(ctx, "other", "value", NoExpiration))
+		// a peer change
+		require.NoError(t, node2.Client().PutString(ctx, "key2", "value2", NoExpiration))
+		change = next()
+		assert.Equal(t, "key2", change.Key)
+		assert.Equal(t, []byte("value2"), change.Entry.Value)
+
+		require.NoError(t, node2.Client().Delete(ctx, "key2"))
+		change = next()
+		assert.Equal(t, "key2", change.Key)
+		assert.True(t, change.Deleted)
+		assert.Nil(t, change.Entry)
+
+		cancel()
+		require.NoError(t, <-done)
+
+		t.Cleanup(func() {
+			assert.NoError(t, node1.Stop(ctx))
+			assert.NoError(t, node2.Stop(ctx))
+			assert.NoError(t, sd1.Close())
+			assert.NoError(t, sd2.Close())
+			srv.Shutdown()
+		})
+	})
+	t.Run("With WatchWithReady", func(t *testing.T) {
+		ctx := context.Background()
+		// start the NATS server
+		srv := startNatsServer(t)
+		node, sd := startNode(t, srv.Addr().String())
+		client := node.Client()
+		require.NoError(t, client.PutString(ctx, "key", "value", NoExpiration))
+
+		var snapshot string
+		changes := make(chan *KeyChange, 10)
+		watchCtx, cancel := context.WithCancel(ctx)
+		done := make(chan error, 1)
+		go func() {
+			done <- client.WatchWithReady(watchCtx, func() error {
+				var err error
+				if snapshot, err = client.GetString(ctx, "key"); err != nil {
+					return err
+				}
+				// a change made once the stream is established is not missed
+				return client.PutString(ctx, "key", "value2", NoExpiration)
+			}, func(change *KeyChange) error {
+				changes <- change
+				return nil
+			}, "key")
+		}()
+
+		select {
+		case change := <-changes:
+			assert.Equal(t, "value", snapshot)
+			assert.Equal(t, []byte("value2"), change.Entry.Value)
+		case <-time.After(5 * time.Second):
+			t.Fatal("change not received")
+		}
+
+		cancel()
+		require.NoError(t, <-done)
+
+		// an error returned by onReady ends the stream
+		err := client.WatchWithReady(ctx, func() error {
+			return ErrKeyNotFound
+		}, func(*KeyChange) error { return nil })
+		assert.ErrorIs(t, err, ErrKeyNotFound)
+
+		t.Cleanup(func() {
+			assert.NoError(t, node.Stop(ctx))
+			assert.NoError(t, sd.Close())
+			srv.Shutdown()
+		})
+	})
+	t.Run("With near cache", func(t *testing.T) {
+		ctx := context.Background()
+		// start the NATS server
+		srv := startNatsServer(t)
+		node1, sd1 := startNode(t, srv.Addr().String())
+		node2, sd2 := startNode(t, srv.Addr().String())
+
+		client := NewClient(node1.config.host, int(node1.config.port), WithNearCache(10, time.Minute))
+		require.NoError(t, node2.Client().PutString(ctx, "key", "value", NoExpiration))
+
+		// the near cache is used once its invalidation stream is established
+		require.Eventually(t, func() bool {
+			value, err := client.GetString(ctx, "key")
+			return err == nil && value == "value" && client.NearCacheStats().Entries == 1
+		}, 5*time.Second, 100*time.Millisecond)
+
+		stats := client.NearCacheStats()
+		value, err := client.GetString(ctx, "key")
+		require.NoError(t, err)
+		assert.Equal(t, "value", value)
+		assert.Equal(t, stats.Hits+1, client.NearCacheStats().Hits)
+
+		// the bypass reads from the cluster
+		value, err = client.GetString(BypassNearCache(ctx), "key")
+		require.NoError(t, err)
+		assert.Equal(t, "value", value)
+		assert.Equal(t, stats.Hits+1, client.NearCacheStats().Hits)
+
+		// a change made on another node invalidates the cached entry
+		require.NoError(t, node2.Client().PutString(ctx, "key", "value2", NoExpiration))
+		require.Eventually(t, func() bool {
+			value, err := client.GetString(ctx, "key")
+			return err == nil && value == "value2"
+		}, 5*time.Second, 100*time.Millisecond)
+
+		require.NoError(t, node2.Client().Delete(ctx, "key"))
+		require.Eventually(t, func() bool {
+			_, err := client.GetString(ctx, "key")
+			return errors.Is(err, ErrKeyNotFound)
+		}, 5*time.Second, 100*time.Millisecond)
+
+		// the near cache is disabled once the node stops
+		require.NoError(t, node1.Stop(ctx))
+		require.Eventually(t, func() bool {
+			_, err := client.GetString(ctx, "key")
+			return err != nil && !errors.Is(err, ErrKeyNotFound)
+		}, 5*time.Second, 100*time.Millisecond)
+		assert.Zero(t, client.NearCacheStats().Entries)
+
+		t.Cleanup(func() {
+			assert.NoError(t, client.Close())
+			assert.NoError(t, node2.Stop(ctx))
+			assert.NoError(t, sd1.Close())
+			assert.NoError(t, sd2.Close())
+			srv.Shutdown()
+		})
+	})
+}
+
+func countingInterceptor(counter *atomic.Int32) connect.UnaryInterceptorFunc {
+	return func(next connect.UnaryFunc) connect.UnaryFunc {
+		return func(ctx context.Context, request connect.AnyRequest) (connect.AnyResponse, error) {
+			counter.Inc()
+			return next(ctx, request)
+		}
+	}
 }
 
 type testCodec struct{}
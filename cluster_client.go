@@ -0,0 +1,517 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2024 Arsene Tochemey Gandote
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package gokv
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"connectrpc.com/connect"
+	"go.uber.org/atomic"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/tochemey/gokv/internal/lib"
+)
+
+// defaultRefreshInterval is the default cluster topology refresh interval
+const defaultRefreshInterval = 10 * time.Second
+
+// memberClient holds the Client of a cluster member
+type memberClient struct {
+	member  *Member
+	address string
+	client  *Client
+}
+
+// ClusterClient defines a cluster topology aware client.
+// Unlike Client, which is bound to a single node, the ClusterClient discovers the cluster members
+// from a set of seed nodes, balances the calls across the healthy members in a round-robin fashion and
+// retries the idempotent calls (Get, Exists, List and ClusterInfo) on another member when one fails.
+// Put and Delete are not retried since a failing member may have applied the write.
+// The cluster topology is refreshed at a regular interval.
+type ClusterClient struct {
+	mu *sync.RWMutex
+
+	seeds   []string
+	members []*memberClient
+	// unhealthy holds the members addresses skipped until the given time
+	unhealthy map[string]time.Time
+	next      *atomic.Uint64
+
+	refreshInterval time.Duration
+	quarantine      time.Duration
+	zone            string
+	clientOptions   []ClientOption
+
+	stopRefresh chan struct{}
+	closed      *atomic.Bool
+}
+
+// NewClusterClient creates an instance of ClusterClient.
+// seeds are the host:port addresses, the port being the client port, of some cluster nodes.
+// The cluster topology is fetched from the first reachable seed.
+func NewClusterClient(ctx context.Context, seeds []string, opts ...ClusterClientOption) (*ClusterClient, error) {
+	if len(seeds) == 0 {
+		return nil, errors.New("cluster client seeds are not set")
+	}
+
+	client := &ClusterClient{
+		mu:              new(sync.RWMutex),
+		seeds:           seeds,
+		unhealthy:       make(map[string]time.Time),
+		next:            atomic.NewUint64(0),
+		refreshInterval: defaultRefreshInterval,
+		stopRefresh:     make(chan struct{}),
+		closed:          atomic.NewBool(false),
+	}
+
+	for _, opt := range opts {
+		opt.Apply(client)
+	}
+
+	if client.quarantine <= 0 {
+		client.quarantine = client.refreshInterval
+	}
+
+	if err := client.Refresh(ctx); err != nil {
+		return nil, err
+	}
+
+	go client.refreshLoop()
+	return client, nil
+}
+
+// Put distributes the key/value pair in the cluster. The entry expires after its TTL when set.
+// It returns ErrEntryExpired when a read entry is put back after its expiry deadline without a new TTL
+func (client *ClusterClient) Put(ctx context.Context, entry *Entry) error {
+	if entry.expired() {
+		return ErrEntryExpired
+	}
+
+	return client.write(func(member *Client) error {
+		return member.Put(ctx, entry)
+	})
+}
+
+// PutProto creates a key/value pair  where the value is a proto message and distributes in the cluster
+func (client *ClusterClient) PutProto(ctx context.Context, key string, value proto.Message, expiration time.Duration) error {
+	bytea, err := proto.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	entry := &Entry{Key: key, Value: bytea, TTL: expiration}
+	return client.Put(ctx, entry)
+}
+
+// PutString creates a key/value pair where the value is a string and distributes in the cluster
+func (client *ClusterClient) PutString(ctx context.Context, key string, value string, expiration time.Duration) error {
+	entry := &Entry{Key: key, Value: []byte(value), TTL: expiration}
+	return client.Put(ctx, entry)
+}
+
+// PutAny distributes the key/value pair in the cluster.
+// A binary encoder is required to properly encode the value.
+func (client *ClusterClient) PutAny(ctx context.Context, key string, value any, expiration time.Duration, codec Codec) error {
+	bytea, err := codec.Encode(value)
+	if err != nil {
+		return err
+	}
+	entry := &Entry{Key: key, Value: bytea, TTL: expiration}
+	return client.Put(ctx, entry)
+}
+
+// Get retrieves the value of the given key from the cluster
+func (client *ClusterClient) Get(ctx context.Context, key string) (*Entry, error) {
+	var entry *Entry
+	err := client.read(ctx, func(member *Client) (err error) {
+		entry, err = member.Get(ctx, key)
+		return err
+	})
+	return entry, err
+}
+
+// GetWithMetadata retrieves the value of the given key from the cluster along with the metadata
+// describing the node state it comes from and how fresh it is
+func (client *ClusterClient) GetWithMetadata(ctx context.Context, key string) (*Entry, *EntryMetadata, error) {
+	var (
+		entry    *Entry
+		metadata *EntryMetadata
+	)
+	err := client.read(ctx, func(member *Client) (err error) {
+		entry, metadata, err = member.GetWithMetadata(ctx, key)
+		return err
+	})
+	return entry, metadata, err
+}
+
+// GetProto retrieves the value of the given from the cluster as protocol buffer message
+// Prior to calling this method one must set a proto message as the value of the key
+func (client *ClusterClient) GetProto(ctx context.Context, key string, dst proto.Message) error {
+	entry, err := client.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	return proto.Unmarshal(entry.Value, dst)
+}
+
+// GetString retrieves the value of the given from the cluster as a string
+// Prior to calling this method one must set a string as the value of the key
+func (client *ClusterClient) GetString(ctx context.Context, key string) (string, error) {
+	entry, err := client.Get(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	return string(entry.Value), nil
+}
+
+// GetAny retrieves the value of the given from the cluster
+// Prior to calling this method one must set a string as the value of the key
+func (client *ClusterClient) GetAny(ctx context.Context, key string, codec Codec) (any, error) {
+	entry, err := client.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return codec.Decode(entry.Value)
+}
+
+// List returns the list of entries at a point in time
+func (client *ClusterClient) List(ctx context.Context) ([]*Entry, error) {
+	var entries []*Entry
+	err := client.read(ctx, func(member *Client) (err error) {
+		entries, err = member.List(ctx)
+		return err
+	})
+	return entries, err
+}
+
+// ListWithMetadata returns the list of entries at a point in time along with the metadata of every entry by key
+// describing the node state it comes from and how fresh it is
+func (client *ClusterClient) ListWithMetadata(ctx context.Context) ([]*Entry, map[string]*EntryMetadata, error) {
+	var (
+		entries  []*Entry
+		metadata map[string]*EntryMetadata
+	)
+	err := client.read(ctx, func(member *Client) (err error) {
+		entries, metadata, err = member.ListWithMetadata(ctx)
+		return err
+	})
+	return entries, metadata, err
+}
+
+// Delete deletes a given key from the cluster
+func (client *ClusterClient) Delete(ctx context.Context, key string) error {
+	return client.write(func(member *Client) error {
+		return member.Delete(ctx, key)
+	})
+}
+
+// Exists checks the existence of a given key in the cluster
+func (client *ClusterClient) Exists(ctx context.Context, key string) (bool, error) {
+	var exists bool
+	err := client.read(ctx, func(member *Client) (err error) {
+		exists, err = member.Exists(ctx, key)
+		return err
+	})
+	return exists, err
+}
+
+// ClusterInfo returns the cluster members as seen by one of the cluster members
+func (client *ClusterClient) ClusterInfo(ctx context.Context) (*ClusterInfo, error) {
+	var info *ClusterInfo
+	err := client.read(ctx, func(member *Client) (err error) {
+		info, err = member.ClusterInfo(ctx)
+		return err
+	})
+	return info, err
+}
+
+// Members returns the cluster members known by the client
+func (client *ClusterClient) Members() []*Member {
+	client.mu.RLock()
+	members := make([]*Member, 0, len(client.members))
+	for _, member := range client.members {
+		members = append(members, member.member)
+	}
+	client.mu.RUnlock()
+	return members
+}
+
+// Refresh fetches the cluster topology from the known members and then from the seeds
+func (client *ClusterClient) Refresh(ctx context.Context) error {
+	if client.closed.Load() {
+		return ErrClientNotConnected
+	}
+
+	members := PreferZone(client.zone, client.Members())
+	addresses := make([]string, 0, len(members)+len(client.seeds))
+	for _, member := range members {
+		addresses = append(addresses, lib.HostPort(member.Host, int(member.Port)))
+	}
+	addresses = append(addresses, client.seeds...)
+
+	var err error
+	for _, address := range addresses {
+		var info *ClusterInfo
+		if info, err = client.fetchTopology(ctx, address); err == nil {
+			client.update(info)
+			return nil
+		}
+	}
+	return fmt.Errorf("failed to fetch the cluster topology: %w", err)
+}
+
+// Close closes the client connections to the cluster members
+func (client *ClusterClient) Close() error {
+	if client.closed.Swap(true) {
+		return nil
+	}
+
+	close(client.stopRefresh)
+	client.mu.Lock()
+	for _, member := range client.members {
+		_ = member.client.Close()
+	}
+	client.members = nil
+	client.mu.Unlock()
+	return nil
+}
+
+// fetchTopology fetches the cluster topology from the member at the given address
+func (client *ClusterClient) fetchTopology(ctx context.Context, address string) (*ClusterInfo, error) {
+	client.mu.RLock()
+	var member *Client
+	for _, candidate := range client.members {
+		if candidate.address == address {
+			member = candidate.client
+			break
+		}
+	}
+	client.mu.RUnlock()
+
+	// use a short-lived client for the seeds that are not known members
+	if member == nil {
+		host, port, err := splitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+		member = NewClient(host, port, client.clientOptions...)
+		defer member.Close()
+	}
+
+	info, err := member.ClusterInfo(ctx)
+	if err != nil {
+		client.markUnhealthy(address)
+		return nil, err
+	}
+	return info, nil
+}
+
+// update replaces the cluster topology with the given one
+func (client *ClusterClient) update(info *ClusterInfo) {
+	members := make([]*Member, 0, len(info.Peers)+1)
+	members = append(members, info.Self)
+	for _, peer := range info.Peers {
+		members = append(members, peer.Member)
+	}
+
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	// the client has been closed in the meantime
+	if client.closed.Load() {
+		return
+	}
+
+	existing := make(map[string]*memberClient, len(client.members))
+	for _, member := range client.members {
+		existing[member.address] = member
+	}
+
+	updated := make([]*memberClient, 0, len(members))
+	for _, member := range members {
+		address := lib.HostPort(member.Host, int(member.Port))
+		if current, ok := existing[address]; ok {
+			current.member = member
+			updated = append(updated, current)
+			delete(existing, address)
+			continue
+		}
+
+		updated = append(updated, &memberClient{
+			member:  member,
+			address: address,
+			client:  NewClient(member.Host, int(member.Port), client.clientOptions...),
+		})
+	}
+
+	// close the clients of the members that left the cluster
+	for address, member := range existing {
+		_ = member.client.Close()
+		delete(client.unhealthy, address)
+	}
+
+	client.members = updated
+}
+
+// refreshLoop refreshes the cluster topology at every refresh interval
+func (client *ClusterClient) refreshLoop() {
+	ticker := time.NewTicker(client.refreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), client.refreshInterval)
+			_ = client.Refresh(ctx)
+			cancel()
+		case <-client.stopRefresh:
+			return
+		}
+	}
+}
+
+// candidates returns the members to call in order.
+// The healthy members come first, starting with the ones of the preferred zone,
+// and are rotated in a round-robin fashion.
+func (client *ClusterClient) candidates() []*memberClient {
+	client.mu.RLock()
+	defer client.mu.RUnlock()
+
+	now := time.Now()
+	var preferred, others, unhealthy []*memberClient
+	for _, member := range client.members {
+		switch until, ok := client.unhealthy[member.address]; {
+		case ok && now.Before(until):
+			unhealthy = append(unhealthy, member)
+		case client.zone != "" && member.member.Zone == client.zone:
+			preferred = append(preferred, member)
+		default:
+			others = append(others, member)
+		}
+	}
+
+	next := client.next.Inc()
+	candidates := make([]*memberClient, 0, len(client.members))
+	candidates = append(candidates, rotate(preferred, next)...)
+	candidates = append(candidates, rotate(others, next)...)
+	// the unhealthy members are the last resort
+	return append(candidates, unhealthy...)
+}
+
+// read calls the given idempotent function on the candidate members until one succeeds
+func (client *ClusterClient) read(ctx context.Context, fn func(member *Client) error) error {
+	if client.closed.Load() {
+		return ErrClientNotConnected
+	}
+
+	candidates := client.candidates()
+	if len(candidates) == 0 {
+		return ErrNoClusterMembers
+	}
+
+	var err error
+	for _, candidate := range candidates {
+		if err = fn(candidate.client); err == nil || !failover(ctx, err) {
+			return err
+		}
+		client.markUnhealthy(candidate.address)
+	}
+	return err
+}
+
+// write calls the given function on the first candidate member
+func (client *ClusterClient) write(fn func(member *Client) error) error {
+	if client.closed.Load() {
+		return ErrClientNotConnected
+	}
+
+	candidates := client.candidates()
+	if len(candidates) == 0 {
+		return ErrNoClusterMembers
+	}
+
+	candidate := candidates[0]
+	err := fn(candidate.client)
+	if err != nil && failover(context.Background(), err) {
+		client.markUnhealthy(candidate.address)
+	}
+	return err
+}
+
+// markUnhealthy skips the member at the given address for the quarantine duration
+func (client *ClusterClient) markUnhealthy(address string) {
+	client.mu.Lock()
+	client.unhealthy[address] = time.Now().Add(client.quarantine)
+	client.mu.Unlock()
+}
+
+// failover returns true when the given error is due to the member failure
+// and the call can be tried on another member
+func failover(ctx context.Context, err error) bool {
+	// the caller gave up
+	if ctx.Err() != nil {
+		return false
+	}
+
+	switch connect.CodeOf(err) {
+	case connect.CodeUnavailable,
+		connect.CodeUnknown,
+		connect.CodeFailedPrecondition,
+		connect.CodeAborted,
+		connect.CodeDeadlineExceeded:
+		return !errors.Is(err, ErrKeyNotFound)
+	default:
+		return false
+	}
+}
+
+// rotate returns the given members rotated by the given offset
+func rotate(members []*memberClient, offset uint64) []*memberClient {
+	if len(members) == 0 {
+		return nil
+	}
+
+	start := int(offset % uint64(len(members)))
+	return append(members[start:len(members):len(members)], members[:start]...)
+}
+
+// splitHostPort splits the given host:port address
+func splitHostPort(address string) (string, int, error) {
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid address %q: %w", address, err)
+	}
+
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid address %q: %w", address, err)
+	}
+	return host, port, nil
+}
@@ -0,0 +1,79 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2024 Arsene Tochemey Gandote
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package gokv
+
+import "time"
+
+// ClusterClientOption is the interface that applies a configuration option to the ClusterClient.
+type ClusterClientOption interface {
+	// Apply sets the ClusterClientOption value of a ClusterClient.
+	Apply(client *ClusterClient)
+}
+
+var _ ClusterClientOption = ClusterClientOptionFunc(nil)
+
+// ClusterClientOptionFunc implements the ClusterClientOption interface.
+type ClusterClientOptionFunc func(client *ClusterClient)
+
+// Apply applies the ClusterClient's option
+func (f ClusterClientOptionFunc) Apply(client *ClusterClient) {
+	f(client)
+}
+
+// WithRefreshInterval sets the interval at which the ClusterClient refreshes the cluster topology.
+// Defaults to 10 seconds
+func WithRefreshInterval(interval time.Duration) ClusterClientOption {
+	return ClusterClientOptionFunc(func(client *ClusterClient) {
+		if interval > 0 {
+			client.refreshInterval = interval
+		}
+	})
+}
+
+// WithQuarantine sets how long a member that failed a call is skipped by the ClusterClient.
+// Defaults to the refresh interval
+func WithQuarantine(duration time.Duration) ClusterClientOption {
+	return ClusterClientOptionFunc(func(client *ClusterClient) {
+		if duration > 0 {
+			client.quarantine = duration
+		}
+	})
+}
+
+// WithPreferredZone sets the zone the ClusterClient runs in.
+// The members of that zone are called first to cut cross-zone traffic.
+func WithPreferredZone(zone string) ClusterClientOption {
+	return ClusterClientOptionFunc(func(client *ClusterClient) {
+		client.zone = zone
+	})
+}
+
+// WithMemberClientOptions sets the options of the Client created for every cluster member,
+// for instance the interceptors, the metrics or the tracing
+func WithMemberClientOptions(opts ...ClientOption) ClusterClientOption {
+	return ClusterClientOptionFunc(func(client *ClusterClient) {
+		client.clientOptions = append(client.clientOptions, opts...)
+	})
+}
@@ -0,0 +1,127 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2024 Arsene Tochemey Gandote
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package gokv
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/travisjeffery/go-dynaport"
+
+	"github.com/tochemey/gokv/internal/lib"
+)
+
+func TestClusterClient(t *testing.T) {
+	t.Run("With failover", func(t *testing.T) {
+		ctx := context.Background()
+		// start the NATS server
+		srv := startNatsServer(t)
+
+		node1, sd1 := startNode(t, srv.Addr().String(), func(config *Config) { config.WithZone("a") })
+		node2, sd2 := startNode(t, srv.Addr().String(), func(config *Config) { config.WithZone("b") })
+		node3, sd3 := startNode(t, srv.Addr().String(), func(config *Config) { config.WithZone("c") })
+
+		// the first seed is not reachable
+		unreachable := lib.HostPort("127.0.0.1", dynaport.Get(1)[0])
+		seeds := []string{unreachable, clientAddress(node1)}
+		client, err := NewClusterClient(ctx, seeds,
+			WithRefreshInterval(500*time.Millisecond),
+			WithPreferredZone("b"))
+		require.NoError(t, err)
+		require.Len(t, client.Members(), 3)
+
+		// the same zone member is called first
+		candidates := client.candidates()
+		require.Len(t, candidates, 3)
+		assert.Equal(t, "b", candidates[0].member.Zone)
+
+		require.NoError(t, client.PutString(ctx, "key", "value", NoExpiration))
+		require.Eventually(t, func() bool {
+			value, err := node3.Client().GetString(ctx, "key")
+			return err == nil && value == "value"
+		}, 5*time.Second, 100*time.Millisecond)
+
+		// stop the preferred zone member which holds the key and another member
+		require.NoError(t, node1.Stop(ctx))
+		require.NoError(t, node2.Stop(ctx))
+
+		// the reads fail over the remaining member which has merged the key
+		for i := 0; i < 5; i++ {
+			value, err := client.GetString(ctx, "key")
+			require.NoError(t, err)
+			assert.Equal(t, "value", value)
+			_, err = client.ClusterInfo(ctx)
+			require.NoError(t, err)
+		}
+
+		_, err = client.Get(ctx, "missing")
+		assert.ErrorIs(t, err, ErrKeyNotFound)
+
+		// the topology converges to the remaining member
+		require.Eventually(t, func() bool {
+			members := client.Members()
+			return len(members) == 1 && members[0].DiscoveryAddress() == node3.HostPort()
+		}, 10*time.Second, 100*time.Millisecond)
+
+		require.NoError(t, client.PutString(ctx, "key", "value3", NoExpiration))
+		value, err := client.GetString(ctx, "key")
+		require.NoError(t, err)
+		assert.Equal(t, "value3", value)
+
+		require.NoError(t, client.Close())
+		_, err = client.Get(ctx, "key")
+		assert.ErrorIs(t, err, ErrClientNotConnected)
+
+		t.Cleanup(func() {
+			assert.NoError(t, node3.Stop(ctx))
+			assert.NoError(t, sd1.Close())
+			assert.NoError(t, sd2.Close())
+			assert.NoError(t, sd3.Close())
+			srv.Shutdown()
+		})
+	})
+	t.Run("With no reachable seed", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		unreachable := lib.HostPort("127.0.0.1", dynaport.Get(1)[0])
+		client, err := NewClusterClient(ctx, []string{unreachable})
+		require.Error(t, err)
+		assert.Nil(t, client)
+	})
+	t.Run("With no seeds", func(t *testing.T) {
+		client, err := NewClusterClient(context.Background(), nil)
+		require.Error(t, err)
+		assert.Nil(t, client)
+	})
+}
+
+// clientAddress returns the node client address
+func clientAddress(node *Node) string {
+	return lib.HostPort(node.config.host, int(node.config.port))
+}
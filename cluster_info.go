@@ -0,0 +1,72 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2024 Arsene Tochemey Gandote
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package gokv
+
+import (
+	"time"
+
+	"github.com/tochemey/gokv/internal/internalpb"
+)
+
+// Peer specifies a cluster peer as seen by a given node
+type Peer struct {
+	// Member specifies the peer member
+	Member *Member
+	// LastSyncTime specifies the last time the node merged the peer state.
+	// It is zero when the peer state has not been merged yet
+	LastSyncTime time.Time
+}
+
+// ClusterInfo specifies the cluster members as seen by a given node
+type ClusterInfo struct {
+	// Self specifies the node serving the cluster information
+	Self *Member
+	// Peers specifies the node peers
+	Peers []*Peer
+	// Ready states whether the node is ready
+	Ready bool
+}
+
+// clusterInfoFromProto returns a ClusterInfo from its protobuf representation
+func clusterInfoFromProto(response *internalpb.ClusterInfoResponse) *ClusterInfo {
+	peers := make([]*Peer, 0, len(response.GetPeers()))
+	for _, peer := range response.GetPeers() {
+		var lastSyncTime time.Time
+		if peer.GetLastSyncTime() != nil {
+			lastSyncTime = peer.GetLastSyncTime().AsTime()
+		}
+
+		peers = append(peers, &Peer{
+			Member:       memberFromNodeMeta(peer.GetMeta()),
+			LastSyncTime: lastSyncTime,
+		})
+	}
+
+	return &ClusterInfo{
+		Self:  memberFromNodeMeta(response.GetSelf()),
+		Peers: peers,
+		Ready: response.GetReady(),
+	}
+}
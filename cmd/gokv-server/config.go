@@ -0,0 +1,454 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2024 Arsene Tochemey Gandote
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+
+	"github.com/tochemey/gokv"
+	"github.com/tochemey/gokv/discovery"
+	"github.com/tochemey/gokv/discovery/dnssd"
+	"github.com/tochemey/gokv/discovery/kubernetes"
+	"github.com/tochemey/gokv/discovery/nats"
+	"github.com/tochemey/gokv/discovery/static"
+	"github.com/tochemey/gokv/log"
+)
+
+const (
+	staticProvider     = "static"
+	dnssdProvider      = "dns-sd"
+	kubernetesProvider = "kubernetes"
+	natsProvider       = "nats"
+)
+
+// serverConfig defines the server configuration read from a YAML or TOML file.
+// Every setting can be overridden by the environment variable set in its env tag
+type serverConfig struct {
+	Host                    string            `yaml:"host" toml:"host" env:"GOKV_HOST"`
+	Port                    uint16            `yaml:"port" toml:"port" env:"GOKV_PORT"`
+	DiscoveryPort           uint16            `yaml:"discovery_port" toml:"discovery_port" env:"GOKV_DISCOVERY_PORT"`
+	ShutdownTimeout         time.Duration     `yaml:"shutdown_timeout" toml:"shutdown_timeout" env:"GOKV_SHUTDOWN_TIMEOUT"`
+	SyncInterval            time.Duration     `yaml:"sync_interval" toml:"sync_interval" env:"GOKV_SYNC_INTERVAL"`
+	MaxJoinAttempts         int               `yaml:"max_join_attempts" toml:"max_join_attempts" env:"GOKV_MAX_JOIN_ATTEMPTS"`
+	JoinRetryInterval       time.Duration     `yaml:"join_retry_interval" toml:"join_retry_interval" env:"GOKV_JOIN_RETRY_INTERVAL"`
+	SingleNodeStart         bool              `yaml:"single_node_start" toml:"single_node_start" env:"GOKV_SINGLE_NODE_START"`
+	RediscoveryInterval     time.Duration     `yaml:"rediscovery_interval" toml:"rediscovery_interval" env:"GOKV_REDISCOVERY_INTERVAL"`
+	ReadTimeout             time.Duration     `yaml:"read_timeout" toml:"read_timeout" env:"GOKV_READ_TIMEOUT"`
+	CleanerJobInterval      time.Duration     `yaml:"cleaner_job_interval" toml:"cleaner_job_interval" env:"GOKV_CLEANER_JOB_INTERVAL"`
+	Cookie                  string            `yaml:"cookie" toml:"cookie" env:"GOKV_COOKIE"`
+	SecretKeys              []string          `yaml:"secret_keys" toml:"secret_keys" env:"GOKV_SECRET_KEYS"`
+	ServerReadTimeout       time.Duration     `yaml:"server_read_timeout" toml:"server_read_timeout" env:"GOKV_SERVER_READ_TIMEOUT"`
+	ServerReadHeaderTimeout time.Duration     `yaml:"server_read_header_timeout" toml:"server_read_header_timeout" env:"GOKV_SERVER_READ_HEADER_TIMEOUT"`
+	ServerWriteTimeout      time.Duration     `yaml:"server_write_timeout" toml:"server_write_timeout" env:"GOKV_SERVER_WRITE_TIMEOUT"`
+	ServerIdleTimeout       time.Duration     `yaml:"server_idle_timeout" toml:"server_idle_timeout" env:"GOKV_SERVER_IDLE_TIMEOUT"`
+	MaxRequestBodySize      int               `yaml:"max_request_body_size" toml:"max_request_body_size" env:"GOKV_MAX_REQUEST_BODY_SIZE"`
+	MaxConcurrentStreams    int               `yaml:"max_concurrent_streams" toml:"max_concurrent_streams" env:"GOKV_MAX_CONCURRENT_STREAMS"`
+	MaxKeySize              int               `yaml:"max_key_size" toml:"max_key_size" env:"GOKV_MAX_KEY_SIZE"`
+	MaxValueSize            int               `yaml:"max_value_size" toml:"max_value_size" env:"GOKV_MAX_VALUE_SIZE"`
+	MaxEntries              int               `yaml:"max_entries" toml:"max_entries" env:"GOKV_MAX_ENTRIES"`
+	MaxTotalSize            int               `yaml:"max_total_size" toml:"max_total_size" env:"GOKV_MAX_TOTAL_SIZE"`
+	EvictionPolicy          string            `yaml:"eviction_policy" toml:"eviction_policy" env:"GOKV_EVICTION_POLICY"`
+	StateChunkSize          int               `yaml:"state_chunk_size" toml:"state_chunk_size" env:"GOKV_STATE_CHUNK_SIZE"`
+	DrainOnStop             bool              `yaml:"drain_on_stop" toml:"drain_on_stop" env:"GOKV_DRAIN_ON_STOP"`
+	TombstoneRetention      time.Duration     `yaml:"tombstone_retention" toml:"tombstone_retention" env:"GOKV_TOMBSTONE_RETENTION"`
+	Zone                    string            `yaml:"zone" toml:"zone" env:"GOKV_ZONE"`
+	Tags                    map[string]string `yaml:"tags" toml:"tags" env:"GOKV_TAGS"`
+	LogLevel                string            `yaml:"log_level" toml:"log_level" env:"GOKV_LOG_LEVEL"`
+	Discovery               discoveryConfig   `yaml:"discovery" toml:"discovery"`
+}
+
+// discoveryConfig defines the discovery provider configuration
+type discoveryConfig struct {
+	// Provider specifies the discovery provider name: static, dns-sd, kubernetes or nats
+	Provider   string           `yaml:"provider" toml:"provider" env:"GOKV_DISCOVERY_PROVIDER"`
+	Static     staticConfig     `yaml:"static" toml:"static"`
+	DNSSD      dnssdConfig      `yaml:"dns_sd" toml:"dns_sd"`
+	Kubernetes kubernetesConfig `yaml:"kubernetes" toml:"kubernetes"`
+	NATS       natsConfig       `yaml:"nats" toml:"nats"`
+}
+
+// staticConfig defines the static discovery provider configuration
+type staticConfig struct {
+	Hosts []string `yaml:"hosts" toml:"hosts" env:"GOKV_STATIC_HOSTS"`
+}
+
+// dnssdConfig defines the dns-sd discovery provider configuration
+type dnssdConfig struct {
+	DomainName string `yaml:"domain_name" toml:"domain_name" env:"GOKV_DNSSD_DOMAIN_NAME"`
+	IPv6       bool   `yaml:"ipv6" toml:"ipv6" env:"GOKV_DNSSD_IPV6"`
+}
+
+// kubernetesConfig defines the kubernetes discovery provider configuration
+type kubernetesConfig struct {
+	Namespace         string            `yaml:"namespace" toml:"namespace" env:"GOKV_KUBERNETES_NAMESPACE"`
+	DiscoveryPortName string            `yaml:"discovery_port_name" toml:"discovery_port_name" env:"GOKV_KUBERNETES_DISCOVERY_PORT_NAME"`
+	PortName          string            `yaml:"port_name" toml:"port_name" env:"GOKV_KUBERNETES_PORT_NAME"`
+	PodLabels         map[string]string `yaml:"pod_labels" toml:"pod_labels" env:"GOKV_KUBERNETES_POD_LABELS"`
+}
+
+// natsConfig defines the nats discovery provider configuration
+type natsConfig struct {
+	Server          string        `yaml:"server" toml:"server" env:"GOKV_NATS_SERVER"`
+	Subject         string        `yaml:"subject" toml:"subject" env:"GOKV_NATS_SUBJECT"`
+	Timeout         time.Duration `yaml:"timeout" toml:"timeout" env:"GOKV_NATS_TIMEOUT"`
+	MaxJoinAttempts int           `yaml:"max_join_attempts" toml:"max_join_attempts" env:"GOKV_NATS_MAX_JOIN_ATTEMPTS"`
+	ReconnectWait   time.Duration `yaml:"reconnect_wait" toml:"reconnect_wait" env:"GOKV_NATS_RECONNECT_WAIT"`
+}
+
+// loadConfig reads the server configuration from the given file, when set, and
+// overrides it with the environment variables
+func loadConfig(path string) (*serverConfig, error) {
+	config := new(serverConfig)
+	if path != "" {
+		bytea, err := os.ReadFile(filepath.Clean(path))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the config file: %w", err)
+		}
+
+		switch ext := strings.ToLower(filepath.Ext(path)); ext {
+		case ".yaml", ".yml":
+			err = yaml.Unmarshal(bytea, config)
+		case ".toml":
+			err = toml.Unmarshal(bytea, config)
+		default:
+			err = fmt.Errorf("unsupported config file format %q", ext)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse the config file: %w", err)
+		}
+	}
+
+	if err := applyEnv(reflect.ValueOf(config).Elem(), os.LookupEnv); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// applyEnv sets the fields of the given struct value from the environment variables named in their env tag
+func applyEnv(value reflect.Value, lookup func(string) (string, bool)) error {
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Field(i)
+		structField := value.Type().Field(i)
+		if field.Kind() == reflect.Struct {
+			if err := applyEnv(field, lookup); err != nil {
+				return err
+			}
+			continue
+		}
+
+		name := structField.Tag.Get("env")
+		if name == "" {
+			continue
+		}
+
+		raw, ok := lookup(name)
+		if !ok {
+			continue
+		}
+
+		if err := setField(field, raw); err != nil {
+			return fmt.Errorf("invalid value of %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// setField sets the given field from its string representation
+func setField(field reflect.Value, raw string) error {
+	switch field.Interface().(type) {
+	case time.Duration:
+		duration, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(duration))
+	case string:
+		field.SetString(raw)
+	case bool:
+		boolean, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		field.SetBool(boolean)
+	case int:
+		integer, err := strconv.Atoi(raw)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(integer))
+	case uint16:
+		integer, err := strconv.ParseUint(raw, 10, 16)
+		if err != nil {
+			return err
+		}
+		field.SetUint(integer)
+	case []string:
+		field.Set(reflect.ValueOf(splitList(raw)))
+	case map[string]string:
+		labels := make(map[string]string)
+		for _, pair := range splitList(raw) {
+			key, val, found := strings.Cut(pair, "=")
+			if !found {
+				return fmt.Errorf("invalid key=value pair %q", pair)
+			}
+			labels[strings.TrimSpace(key)] = strings.TrimSpace(val)
+		}
+		field.Set(reflect.ValueOf(labels))
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+	return nil
+}
+
+// splitList splits a comma separated list
+func splitList(raw string) []string {
+	var items []string
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// nodeConfig builds the node configuration
+func (config *serverConfig) nodeConfig() (*gokv.Config, error) {
+	logger, err := config.logger()
+	if err != nil {
+		return nil, err
+	}
+
+	provider, err := config.provider(logger)
+	if err != nil {
+		return nil, err
+	}
+
+	evictionPolicy, err := config.evictionPolicy()
+	if err != nil {
+		return nil, err
+	}
+
+	nodeConfig := gokv.NewConfig().
+		WithPort(config.Port).
+		WithDiscoveryPort(config.DiscoveryPort).
+		WithDiscoveryProvider(provider).
+		WithLogger(logger)
+
+	if config.Host != "" {
+		nodeConfig.WithHost(config.Host)
+	}
+
+	if config.ShutdownTimeout > 0 {
+		nodeConfig.WithShutdownTimeout(config.ShutdownTimeout)
+	}
+
+	if config.SyncInterval > 0 {
+		nodeConfig.WithSyncInterval(config.SyncInterval)
+	}
+
+	if config.MaxJoinAttempts > 0 {
+		nodeConfig.WithMaxJoinAttempts(config.MaxJoinAttempts)
+	}
+
+	if config.JoinRetryInterval > 0 {
+		nodeConfig.WithJoinRetryInterval(config.JoinRetryInterval)
+	}
+
+	nodeConfig.WithSingleNodeStart(config.SingleNodeStart)
+
+	if config.RediscoveryInterval > 0 {
+		nodeConfig.WithRediscoveryInterval(config.RediscoveryInterval)
+	}
+
+	if config.ReadTimeout > 0 {
+		nodeConfig.WithReadTimeout(config.ReadTimeout)
+	}
+
+	if config.CleanerJobInterval > 0 {
+		nodeConfig.WithCleanerJobInterval(config.CleanerJobInterval)
+	}
+
+	if config.ServerReadTimeout > 0 {
+		nodeConfig.WithServerReadTimeout(config.ServerReadTimeout)
+	}
+
+	if config.ServerReadHeaderTimeout > 0 {
+		nodeConfig.WithServerReadHeaderTimeout(config.ServerReadHeaderTimeout)
+	}
+
+	if config.ServerWriteTimeout > 0 {
+		nodeConfig.WithServerWriteTimeout(config.ServerWriteTimeout)
+	}
+
+	if config.ServerIdleTimeout > 0 {
+		nodeConfig.WithServerIdleTimeout(config.ServerIdleTimeout)
+	}
+
+	if config.MaxRequestBodySize > 0 {
+		nodeConfig.WithMaxRequestBodySize(int64(config.MaxRequestBodySize))
+	}
+
+	if config.MaxConcurrentStreams > 0 {
+		nodeConfig.WithMaxConcurrentStreams(uint32(config.MaxConcurrentStreams))
+	}
+
+	if config.MaxKeySize > 0 {
+		nodeConfig.WithMaxKeySize(config.MaxKeySize)
+	}
+
+	if config.MaxValueSize > 0 {
+		nodeConfig.WithMaxValueSize(config.MaxValueSize)
+	}
+
+	if config.MaxEntries > 0 {
+		nodeConfig.WithMaxEntries(config.MaxEntries)
+	}
+
+	if config.MaxTotalSize > 0 {
+		nodeConfig.WithMaxTotalSize(int64(config.MaxTotalSize))
+	}
+
+	nodeConfig.WithEvictionPolicy(evictionPolicy)
+
+	if config.StateChunkSize > 0 {
+		nodeConfig.WithStateChunkSize(config.StateChunkSize)
+	}
+
+	nodeConfig.WithDrainOnStop(config.DrainOnStop)
+
+	if config.TombstoneRetention > 0 {
+		nodeConfig.WithTombstoneRetention(config.TombstoneRetention)
+	}
+
+	if config.Zone != "" {
+		nodeConfig.WithZone(config.Zone)
+	}
+
+	if len(config.Tags) > 0 {
+		nodeConfig.WithTags(config.Tags)
+	}
+
+	if len(config.SecretKeys) > 0 {
+		nodeConfig.WithEncryption(config.Cookie, config.SecretKeys)
+	}
+
+	if err := nodeConfig.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid node configuration: %w", err)
+	}
+	return nodeConfig, nil
+}
+
+// logger returns the logger with the configured log level
+func (config *serverConfig) logger() (log.Logger, error) {
+	level := log.InfoLevel
+	switch strings.ToLower(config.LogLevel) {
+	case "", "info":
+	case "debug":
+		level = log.DebugLevel
+	case "warn", "warning":
+		level = log.WarningLevel
+	case "error":
+		level = log.ErrorLevel
+	default:
+		return nil, fmt.Errorf("unsupported log level %q", config.LogLevel)
+	}
+	return log.New(level, os.Stdout), nil
+}
+
+// evictionPolicy returns the configured eviction policy
+func (config *serverConfig) evictionPolicy() (gokv.EvictionPolicy, error) {
+	switch strings.ToLower(config.EvictionPolicy) {
+	case "", "none":
+		return gokv.NoEviction, nil
+	case "lru":
+		return gokv.LRU, nil
+	case "lfu":
+		return gokv.LFU, nil
+	case "ttl-first":
+		return gokv.TTLFirst, nil
+	default:
+		return gokv.NoEviction, fmt.Errorf("unsupported eviction policy %q", config.EvictionPolicy)
+	}
+}
+
+// provider creates the configured discovery provider
+func (config *serverConfig) provider(logger log.Logger) (discovery.Provider, error) {
+	disco := config.Discovery
+	switch disco.Provider {
+	case staticProvider:
+		providerConfig := &static.Config{Hosts: disco.Static.Hosts}
+		if err := providerConfig.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid static discovery configuration: %w", err)
+		}
+		return static.NewDiscovery(providerConfig), nil
+	case dnssdProvider:
+		ipv6 := disco.DNSSD.IPv6
+		providerConfig := &dnssd.Config{DomainName: disco.DNSSD.DomainName, IPv6: &ipv6}
+		if err := providerConfig.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid dns-sd discovery configuration: %w", err)
+		}
+		return dnssd.NewDiscovery(providerConfig), nil
+	case kubernetesProvider:
+		providerConfig := &kubernetes.Config{
+			Namespace:         disco.Kubernetes.Namespace,
+			DiscoveryPortName: disco.Kubernetes.DiscoveryPortName,
+			PortName:          disco.Kubernetes.PortName,
+			PodLabels:         disco.Kubernetes.PodLabels,
+		}
+		if err := providerConfig.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid kubernetes discovery configuration: %w", err)
+		}
+		return kubernetes.NewDiscovery(providerConfig), nil
+	case natsProvider:
+		providerConfig := &nats.Config{
+			Server:          disco.NATS.Server,
+			Subject:         disco.NATS.Subject,
+			Timeout:         disco.NATS.Timeout,
+			MaxJoinAttempts: disco.NATS.MaxJoinAttempts,
+			ReconnectWait:   disco.NATS.ReconnectWait,
+			Host:            config.Host,
+			DiscoveryPort:   config.DiscoveryPort,
+		}
+		if err := providerConfig.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid nats discovery configuration: %w", err)
+		}
+		return nats.NewDiscovery(providerConfig, nats.WithLogger(logger)), nil
+	case "":
+		return nil, errors.New("discovery provider is not set")
+	default:
+		return nil, fmt.Errorf("unsupported discovery provider %q", disco.Provider)
+	}
+}
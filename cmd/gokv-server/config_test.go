@@ -0,0 +1,170 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2024 Arsene Tochemey Gandote
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tochemey/gokv"
+	"github.com/tochemey/gokv/discovery/kubernetes"
+	"github.com/tochemey/gokv/discovery/nats"
+	"github.com/tochemey/gokv/discovery/static"
+)
+
+const yamlConfig = `
+host: 127.0.0.1
+port: 3320
+discovery_port: 3322
+sync_interval: 2s
+max_join_attempts: 3
+log_level: debug
+discovery:
+  provider: static
+  static:
+    hosts:
+      - 127.0.0.1:3322
+      - 127.0.0.1:3324
+`
+
+const tomlConfig = `
+host = "127.0.0.1"
+port = 3320
+discovery_port = 3322
+shutdown_timeout = "5s"
+
+[discovery]
+provider = "nats"
+
+[discovery.nats]
+server = "nats://127.0.0.1:4222"
+subject = "gokv"
+`
+
+func TestLoadConfig(t *testing.T) {
+	t.Run("With YAML file", func(t *testing.T) {
+		path := writeFile(t, "config.yaml", yamlConfig)
+		config, err := loadConfig(path)
+		require.NoError(t, err)
+		assert.Equal(t, "127.0.0.1", config.Host)
+		assert.EqualValues(t, 3320, config.Port)
+		assert.EqualValues(t, 3322, config.DiscoveryPort)
+		assert.Equal(t, 2*time.Second, config.SyncInterval)
+		assert.Equal(t, 3, config.MaxJoinAttempts)
+		assert.Equal(t, staticProvider, config.Discovery.Provider)
+		assert.Equal(t, []string{"127.0.0.1:3322", "127.0.0.1:3324"}, config.Discovery.Static.Hosts)
+
+		nodeConfig, err := config.nodeConfig()
+		require.NoError(t, err)
+		assert.NotNil(t, nodeConfig)
+
+		provider, err := config.provider(nil)
+		require.NoError(t, err)
+		assert.IsType(t, new(static.Discovery), provider)
+	})
+	t.Run("With TOML file", func(t *testing.T) {
+		path := writeFile(t, "config.toml", tomlConfig)
+		config, err := loadConfig(path)
+		require.NoError(t, err)
+		assert.Equal(t, "127.0.0.1", config.Host)
+		assert.Equal(t, 5*time.Second, config.ShutdownTimeout)
+		assert.Equal(t, natsProvider, config.Discovery.Provider)
+		assert.Equal(t, "nats://127.0.0.1:4222", config.Discovery.NATS.Server)
+
+		provider, err := config.provider(nil)
+		require.NoError(t, err)
+		assert.IsType(t, new(nats.Discovery), provider)
+	})
+	t.Run("With environment variables", func(t *testing.T) {
+		path := writeFile(t, "config.yaml", yamlConfig)
+		t.Setenv("GOKV_PORT", "4420")
+		t.Setenv("GOKV_SYNC_INTERVAL", "500ms")
+		t.Setenv("GOKV_DISCOVERY_PROVIDER", kubernetesProvider)
+		t.Setenv("GOKV_KUBERNETES_NAMESPACE", "default")
+		t.Setenv("GOKV_KUBERNETES_DISCOVERY_PORT_NAME", "discovery-port")
+		t.Setenv("GOKV_KUBERNETES_PORT_NAME", "client-port")
+		t.Setenv("GOKV_KUBERNETES_POD_LABELS", "app=gokv, tier=cache")
+
+		config, err := loadConfig(path)
+		require.NoError(t, err)
+		assert.EqualValues(t, 4420, config.Port)
+		assert.Equal(t, 500*time.Millisecond, config.SyncInterval)
+		assert.Equal(t, kubernetesProvider, config.Discovery.Provider)
+		assert.True(t, reflect.DeepEqual(map[string]string{"app": "gokv", "tier": "cache"}, config.Discovery.Kubernetes.PodLabels))
+
+		provider, err := config.provider(nil)
+		require.NoError(t, err)
+		assert.IsType(t, new(kubernetes.Discovery), provider)
+	})
+	t.Run("With invalid environment variable", func(t *testing.T) {
+		t.Setenv("GOKV_PORT", "invalid")
+		config, err := loadConfig("")
+		require.Error(t, err)
+		assert.Nil(t, config)
+	})
+	t.Run("With unsupported file format", func(t *testing.T) {
+		path := writeFile(t, "config.json", "{}")
+		config, err := loadConfig(path)
+		require.Error(t, err)
+		assert.Nil(t, config)
+	})
+	t.Run("With unsupported provider", func(t *testing.T) {
+		config := &serverConfig{Discovery: discoveryConfig{Provider: "consul"}}
+		_, err := config.provider(nil)
+		assert.EqualError(t, err, `unsupported discovery provider "consul"`)
+
+		config = new(serverConfig)
+		_, err = config.provider(nil)
+		assert.EqualError(t, err, "discovery provider is not set")
+	})
+	t.Run("With invalid log level", func(t *testing.T) {
+		config := &serverConfig{LogLevel: "verbose"}
+		_, err := config.nodeConfig()
+		assert.Error(t, err)
+	})
+	t.Run("With eviction policy", func(t *testing.T) {
+		config := &serverConfig{EvictionPolicy: "LFU"}
+		policy, err := config.evictionPolicy()
+		require.NoError(t, err)
+		assert.Equal(t, gokv.LFU, policy)
+
+		config = &serverConfig{EvictionPolicy: "random"}
+		_, err = config.evictionPolicy()
+		assert.EqualError(t, err, `unsupported eviction policy "random"`)
+	})
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
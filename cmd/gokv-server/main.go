@@ -0,0 +1,83 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2024 Arsene Tochemey Gandote
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+// Command gokv-server runs a standalone Go-KV cluster node.
+// The node is configured from a YAML or TOML file and environment variables.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/tochemey/gokv"
+)
+
+func main() {
+	flags := flag.NewFlagSet("gokv-server", flag.ExitOnError)
+	path := flags.String("config", os.Getenv("GOKV_CONFIG"), "the YAML or TOML configuration file. Defaults to the GOKV_CONFIG environment variable")
+	if err := flags.Parse(os.Args[1:]); err != nil {
+		exit(err)
+	}
+
+	config, err := loadConfig(*path)
+	if err != nil {
+		exit(err)
+	}
+
+	nodeConfig, err := config.nodeConfig()
+	if err != nil {
+		exit(err)
+	}
+
+	node, err := gokv.NewNode(nodeConfig)
+	if err != nil {
+		exit(err)
+	}
+
+	ctx := context.Background()
+	if err := node.Start(ctx); err != nil {
+		exit(err)
+	}
+
+	// wait for the termination signal
+	interruptSignal := make(chan os.Signal, 1)
+	signal.Notify(interruptSignal, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+	<-interruptSignal
+
+	// the entries handoff, when the drain on stop is enabled, and the shutdown
+	// are each bounded by the node shutdown timeout
+	if err := node.Stop(ctx); err != nil {
+		exit(err)
+	}
+}
+
+// exit prints the given error and exits
+func exit(err error) {
+	fmt.Fprintf(os.Stderr, "gokv-server: %v\n", err)
+	os.Exit(1)
+}
@@ -0,0 +1,328 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2024 Arsene Tochemey Gandote
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/tochemey/gokv"
+)
+
+// commands implements the gokvctl commands
+type commands struct {
+	client    *gokv.Client
+	formatter *formatter
+	opts      *options
+	in        io.Reader
+	out       io.Writer
+}
+
+// get retrieves the value of the given key
+func (c *commands) get(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: get <key>")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.opts.timeout)
+	defer cancel()
+
+	entry, err := c.client.Get(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	return c.formatter.value(c.out, entry)
+}
+
+// put distributes the given key/value pair
+func (c *commands) put(ctx context.Context, args []string) error {
+	if len(args) != 2 {
+		return errors.New("usage: put <key> <value>")
+	}
+
+	value := []byte(args[1])
+	if args[1] == "-" {
+		bytea, err := io.ReadAll(c.in)
+		if err != nil {
+			return fmt.Errorf("failed to read the value from stdin: %w", err)
+		}
+		value = bytea
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.opts.timeout)
+	defer cancel()
+	return c.client.Put(ctx, &gokv.Entry{Key: args[0], Value: value, TTL: c.expiration()})
+}
+
+// delete removes the given key
+func (c *commands) delete(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: delete <key>")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.opts.timeout)
+	defer cancel()
+	return c.client.Delete(ctx, args[0])
+}
+
+// exists checks the existence of the given key
+func (c *commands) exists(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: exists <key>")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.opts.timeout)
+	defer cancel()
+
+	exists, err := c.client.Exists(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	return c.formatter.exists(c.out, args[0], exists)
+}
+
+// list lists the cluster entries
+func (c *commands) list(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, c.opts.timeout)
+	defer cancel()
+
+	entries, err := c.client.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if err := c.formatter.entry(c.out, entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// watch writes the current values of the given keys, or of all the keys when none is given,
+// and then streams their changes until the command is interrupted
+func (c *commands) watch(ctx context.Context, args []string) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// the snapshot is taken once the stream is established so that no change is missed
+	return c.client.WatchWithReady(ctx, func() error {
+		current, err := c.snapshot(ctx, args)
+		if err != nil {
+			return err
+		}
+
+		for key, value := range current {
+			if err := c.formatter.change(c.out, changePut, key, value); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, func(change *gokv.KeyChange) error {
+		if change.Deleted {
+			return c.formatter.change(c.out, changeDelete, change.Key, nil)
+		}
+		return c.formatter.change(c.out, changePut, change.Key, change.Entry.Value)
+	}, args...)
+}
+
+// snapshot returns the current values of the given keys or of all the keys when none is given
+func (c *commands) snapshot(ctx context.Context, keys []string) (map[string][]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.opts.timeout)
+	defer cancel()
+
+	values := make(map[string][]byte)
+	if len(keys) == 0 {
+		entries, err := c.client.List(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, entry := range entries {
+			values[entry.Key] = entry.Value
+		}
+		return values, nil
+	}
+
+	for _, key := range keys {
+		entry, err := c.client.Get(ctx, key)
+		if err != nil {
+			if errors.Is(err, gokv.ErrKeyNotFound) {
+				continue
+			}
+			return nil, err
+		}
+		values[key] = entry.Value
+	}
+	return values, nil
+}
+
+// members lists the cluster members
+func (c *commands) members(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, c.opts.timeout)
+	defer cancel()
+
+	info, err := c.client.ClusterInfo(ctx)
+	if err != nil {
+		return err
+	}
+	return c.formatter.members(c.out, info)
+}
+
+// export writes all the cluster entries as JSON lines
+func (c *commands) export(ctx context.Context) error {
+	out := c.out
+	if c.opts.file != "" {
+		file, err := os.Create(c.opts.file)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.opts.timeout)
+	defer cancel()
+
+	entries, _, err := c.client.ListWithMetadata(ctx)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(out)
+	for _, entry := range entries {
+		if err := encoder.Encode(newJSONEntry(entry)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// importEntries reads entries from JSON lines and distributes them in the cluster
+func (c *commands) importEntries(ctx context.Context) error {
+	in := c.in
+	if c.opts.file != "" {
+		file, err := os.Open(c.opts.file)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		in = file
+	}
+
+	scanner := bufio.NewScanner(in)
+	// entries can be large
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		entry := new(jsonEntry)
+		if err := json.Unmarshal(scanner.Bytes(), entry); err != nil {
+			return fmt.Errorf("invalid entry at line %d: %w", line, err)
+		}
+
+		if err := c.putEntry(ctx, entry); err != nil {
+			return fmt.Errorf("failed to import entry at line %d: %w", line, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// putEntry distributes the given imported entry.
+// The entry keeps its exported expiry deadline unless an expiration is set on the command line
+// and an entry that has expired since its export is skipped
+func (c *commands) putEntry(ctx context.Context, entry *jsonEntry) error {
+	ttl := c.expiration()
+	if ttl == gokv.NoExpiration && entry.ExpiresAt != nil {
+		if ttl = time.Until(*entry.ExpiresAt); ttl <= 0 {
+			return nil
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.opts.timeout)
+	defer cancel()
+	return c.client.Put(ctx, &gokv.Entry{Key: entry.Key, Value: entry.Value, TTL: ttl})
+}
+
+// expiration returns the entries expiration set on the command line
+func (c *commands) expiration() time.Duration {
+	if c.opts.ttl <= 0 {
+		return gokv.NoExpiration
+	}
+	return c.opts.ttl
+}
+
+// health checks the node health and readiness
+func health(opts *options, out io.Writer) error {
+	httpClient := &http.Client{Timeout: opts.timeout}
+	healthy := true
+	for _, endpoint := range []string{"healthz", "readyz"} {
+		status := "ok"
+		response, err := httpClient.Get(fmt.Sprintf("http://%s/%s", opts.address, endpoint))
+		switch {
+		case err != nil:
+			status = err.Error()
+			healthy = false
+		case response.StatusCode != http.StatusOK:
+			status = response.Status
+			healthy = false
+		}
+
+		if response != nil {
+			_ = response.Body.Close()
+		}
+
+		if opts.output == outputJSON {
+			if err := json.NewEncoder(out).Encode(map[string]string{"check": endpoint, "status": status}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if _, err := fmt.Fprintf(out, "%s\t%s\n", endpoint, status); err != nil {
+			return err
+		}
+	}
+
+	if !healthy {
+		return fmt.Errorf("node %s is not healthy", opts.address)
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2024 Arsene Tochemey Gandote
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+// Command gokvctl is a command line tool to operate a Go-KV cluster.
+// It connects to any node of the cluster by its host:port address.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/tochemey/gokv"
+)
+
+const usage = `gokvctl is a command line tool to operate a Go-KV cluster.
+
+Usage:
+  gokvctl [flags] <command> [arguments]
+
+Commands:
+  get <key>            retrieves the value of the given key
+  put <key> <value>    distributes the key/value pair. Use - as value to read it from stdin
+  delete <key>         removes the given key from the cluster
+  exists <key>         checks the existence of the given key
+  list                 lists the entries of the cluster
+  watch [key...]       watches the changes of the given keys or all keys when none is given
+  members              lists the cluster members as seen by the node
+  export               exports all entries as JSON lines
+  import               imports entries from JSON lines
+  health               checks the node health and readiness
+
+Flags:
+`
+
+// options defines the global command line options
+type options struct {
+	address string
+	output  string
+	timeout time.Duration
+	ttl     time.Duration
+	file    string
+}
+
+func main() {
+	opts := new(options)
+	flags := flag.NewFlagSet("gokvctl", flag.ExitOnError)
+	flags.StringVar(&opts.address, "addr", "", "the node host:port address where port is the node client port")
+	flags.StringVar(&opts.output, "o", outputRaw, "the output format: raw, json or hex")
+	flags.DurationVar(&opts.timeout, "timeout", 5*time.Second, "the timeout of a command call")
+	flags.DurationVar(&opts.ttl, "ttl", 0, "the expiration of the entries set by put and import. Zero means no expiration, or the exported expiry deadline on import")
+	flags.StringVar(&opts.file, "file", "", "the file used by export and import. Defaults to stdout and stdin")
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), usage)
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(os.Args[1:]); err != nil {
+		exit(err)
+	}
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(opts, flags.Arg(0), flags.Args()[1:], os.Stdin, os.Stdout); err != nil {
+		exit(err)
+	}
+}
+
+// run executes the given command
+func run(opts *options, command string, args []string, in io.Reader, out io.Writer) error {
+	formatter, err := newFormatter(opts.output)
+	if err != nil {
+		return err
+	}
+
+	host, port, err := splitAddress(opts.address)
+	if err != nil {
+		return err
+	}
+
+	// health does not require the cluster client
+	if command == "health" {
+		return health(opts, out)
+	}
+
+	client := gokv.NewClient(host, port)
+	defer func() {
+		_ = client.Close()
+	}()
+
+	ctx := context.Background()
+	cmd := &commands{
+		client:    client,
+		formatter: formatter,
+		opts:      opts,
+		in:        in,
+		out:       out,
+	}
+
+	switch command {
+	case "get":
+		return cmd.get(ctx, args)
+	case "put":
+		return cmd.put(ctx, args)
+	case "delete":
+		return cmd.delete(ctx, args)
+	case "exists":
+		return cmd.exists(ctx, args)
+	case "list":
+		return cmd.list(ctx)
+	case "watch":
+		return cmd.watch(ctx, args)
+	case "members":
+		return cmd.members(ctx)
+	case "export":
+		return cmd.export(ctx)
+	case "import":
+		return cmd.importEntries(ctx)
+	default:
+		return fmt.Errorf("unknown command %q", command)
+	}
+}
+
+// splitAddress returns the host and port of the given address
+func splitAddress(address string) (string, int, error) {
+	if address == "" {
+		return "", 0, errors.New("the node address is required")
+	}
+
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid node address %q: %w", address, err)
+	}
+
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid node port %q: %w", portStr, err)
+	}
+	return host, port, nil
+}
+
+// exit prints the given error and exits
+func exit(err error) {
+	fmt.Fprintf(os.Stderr, "gokvctl: %v\n", err)
+	os.Exit(1)
+}
@@ -0,0 +1,171 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2024 Arsene Tochemey Gandote
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/tochemey/gokv"
+)
+
+const (
+	outputRaw  = "raw"
+	outputJSON = "json"
+	outputHex  = "hex"
+)
+
+const (
+	changePut    = "put"
+	changeDelete = "delete"
+)
+
+// jsonEntry defines the JSON representation of an entry.
+// This is the representation used by export and import
+type jsonEntry struct {
+	Key         string     `json:"key"`
+	Value       []byte     `json:"value"`
+	LastUpdated *time.Time `json:"last_updated,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	Version     uint64     `json:"version,omitempty"`
+	Origin      string     `json:"origin,omitempty"`
+}
+
+// newJSONEntry returns the JSON representation of the given entry
+func newJSONEntry(entry *gokv.Entry) *jsonEntry {
+	result := &jsonEntry{
+		Key:     entry.Key,
+		Value:   entry.Value,
+		Version: entry.Version,
+		Origin:  entry.Origin,
+	}
+	if !entry.LastUpdated.IsZero() {
+		result.LastUpdated = &entry.LastUpdated
+	}
+	if !entry.ExpiresAt.IsZero() {
+		result.ExpiresAt = &entry.ExpiresAt
+	}
+	return result
+}
+
+// jsonChange defines the JSON representation of an entry change
+type jsonChange struct {
+	Type  string `json:"type"`
+	Key   string `json:"key"`
+	Value []byte `json:"value,omitempty"`
+}
+
+// formatter writes the commands results in the requested output format
+type formatter struct {
+	format string
+}
+
+// newFormatter creates an instance of formatter
+func newFormatter(format string) (*formatter, error) {
+	switch format {
+	case outputRaw, outputJSON, outputHex:
+		return &formatter{format: format}, nil
+	default:
+		return nil, fmt.Errorf("unsupported output format %q", format)
+	}
+}
+
+// value writes the value of the given entry
+func (f *formatter) value(out io.Writer, entry *gokv.Entry) error {
+	switch f.format {
+	case outputJSON:
+		return json.NewEncoder(out).Encode(newJSONEntry(entry))
+	case outputHex:
+		_, err := fmt.Fprintln(out, hex.EncodeToString(entry.Value))
+		return err
+	default:
+		_, err := out.Write(entry.Value)
+		return err
+	}
+}
+
+// entry writes the given entry key and value
+func (f *formatter) entry(out io.Writer, entry *gokv.Entry) error {
+	switch f.format {
+	case outputJSON:
+		return json.NewEncoder(out).Encode(newJSONEntry(entry))
+	case outputHex:
+		_, err := fmt.Fprintf(out, "%s\t%s\n", entry.Key, hex.EncodeToString(entry.Value))
+		return err
+	default:
+		_, err := fmt.Fprintf(out, "%s\t%s\n", entry.Key, entry.Value)
+		return err
+	}
+}
+
+// exists writes the existence of the given key
+func (f *formatter) exists(out io.Writer, key string, exists bool) error {
+	if f.format == outputJSON {
+		return json.NewEncoder(out).Encode(map[string]any{"key": key, "exists": exists})
+	}
+	_, err := fmt.Fprintln(out, exists)
+	return err
+}
+
+// change writes the change of the given key.
+// The entry value is nil when the key has been deleted
+func (f *formatter) change(out io.Writer, changeType, key string, value []byte) error {
+	switch f.format {
+	case outputJSON:
+		return json.NewEncoder(out).Encode(&jsonChange{Type: changeType, Key: key, Value: value})
+	case outputHex:
+		_, err := fmt.Fprintf(out, "%s\t%s\t%s\n", changeType, key, hex.EncodeToString(value))
+		return err
+	default:
+		_, err := fmt.Fprintf(out, "%s\t%s\t%s\n", changeType, key, value)
+		return err
+	}
+}
+
+// members writes the cluster members
+func (f *formatter) members(out io.Writer, info *gokv.ClusterInfo) error {
+	if f.format == outputJSON {
+		return json.NewEncoder(out).Encode(info)
+	}
+
+	if _, err := fmt.Fprintf(out, "%s\t%s\tself\tready=%t\n", info.Self.Name, info.Self.DiscoveryAddress(), info.Ready); err != nil {
+		return err
+	}
+
+	for _, peer := range info.Peers {
+		lastSync := "never"
+		if !peer.LastSyncTime.IsZero() {
+			lastSync = peer.LastSyncTime.Format("2006-01-02T15:04:05.000Z07:00")
+		}
+
+		if _, err := fmt.Fprintf(out, "%s\t%s\tpeer\tlast-sync=%s\n", peer.Member.Name, peer.Member.DiscoveryAddress(), lastSync); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2024 Arsene Tochemey Gandote
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tochemey/gokv"
+)
+
+func TestFormatter(t *testing.T) {
+	entry := &gokv.Entry{Key: "key", Value: []byte("value")}
+	testCases := []struct {
+		format   string
+		value    string
+		entry    string
+		exists   string
+		change   string
+		deletion string
+	}{
+		{
+			format:   outputRaw,
+			value:    "value",
+			entry:    "key\tvalue\n",
+			exists:   "true\n",
+			change:   "put\tkey\tvalue\n",
+			deletion: "delete\tkey\t\n",
+		},
+		{
+			format:   outputHex,
+			value:    "76616c7565\n",
+			entry:    "key\t76616c7565\n",
+			exists:   "true\n",
+			change:   "put\tkey\t76616c7565\n",
+			deletion: "delete\tkey\t\n",
+		},
+		{
+			format:   outputJSON,
+			value:    "{\"key\":\"key\",\"value\":\"dmFsdWU=\"}\n",
+			entry:    "{\"key\":\"key\",\"value\":\"dmFsdWU=\"}\n",
+			exists:   "{\"exists\":true,\"key\":\"key\"}\n",
+			change:   "{\"type\":\"put\",\"key\":\"key\",\"value\":\"dmFsdWU=\"}\n",
+			deletion: "{\"type\":\"delete\",\"key\":\"key\"}\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.format, func(t *testing.T) {
+			formatter, err := newFormatter(tc.format)
+			require.NoError(t, err)
+
+			out := new(bytes.Buffer)
+			require.NoError(t, formatter.value(out, entry))
+			assert.Equal(t, tc.value, out.String())
+
+			out.Reset()
+			require.NoError(t, formatter.entry(out, entry))
+			assert.Equal(t, tc.entry, out.String())
+
+			out.Reset()
+			require.NoError(t, formatter.exists(out, entry.Key, true))
+			assert.Equal(t, tc.exists, out.String())
+
+			out.Reset()
+			require.NoError(t, formatter.change(out, changePut, entry.Key, entry.Value))
+			assert.Equal(t, tc.change, out.String())
+
+			out.Reset()
+			require.NoError(t, formatter.change(out, changeDelete, entry.Key, nil))
+			assert.Equal(t, tc.deletion, out.String())
+		})
+	}
+
+	t.Run("With unsupported format", func(t *testing.T) {
+		formatter, err := newFormatter("yaml")
+		require.Error(t, err)
+		assert.Nil(t, formatter)
+	})
+}
+
+func TestJSONEntry(t *testing.T) {
+	updated := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	entry := &gokv.Entry{
+		Key:         "key",
+		Value:       []byte("value"),
+		LastUpdated: updated,
+		ExpiresAt:   updated.Add(time.Hour),
+		Version:     3,
+		Origin:      "node",
+	}
+
+	bytea, err := json.Marshal(newJSONEntry(entry))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"key":"key","value":"dmFsdWU=","last_updated":"2024-01-01T00:00:00Z","expires_at":"2024-01-01T01:00:00Z","version":3,"origin":"node"}`, string(bytea))
+
+	// the metadata of an entry that has not been read is omitted
+	bytea, err = json.Marshal(newJSONEntry(&gokv.Entry{Key: "key", Value: []byte("value")}))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"key":"key","value":"dmFsdWU="}`, string(bytea))
+}
+
+func TestSplitAddress(t *testing.T) {
+	host, port, err := splitAddress("127.0.0.1:3320")
+	require.NoError(t, err)
+	assert.Equal(t, "127.0.0.1", host)
+	assert.Equal(t, 3320, port)
+
+	_, _, err = splitAddress("")
+	assert.Error(t, err)
+
+	_, _, err = splitAddress("127.0.0.1")
+	assert.Error(t, err)
+
+	_, _, err = splitAddress("127.0.0.1:port")
+	assert.Error(t, err)
+}
@@ -25,9 +25,14 @@
 package gokv
 
 import (
+	nethttp "net/http"
 	"os"
 	"time"
 
+	"connectrpc.com/connect"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+
 	"github.com/tochemey/gokv/discovery"
 	"github.com/tochemey/gokv/internal/validation"
 	"github.com/tochemey/gokv/log"
@@ -39,7 +44,14 @@ type Config struct {
 	// This specifies the number of attempts to make when trying to join an existing cluster
 	maxJoinAttempts int
 	// specifies the join retry interval
+	// This is the initial delay of the exponential backoff used when joining an existing cluster
 	joinRetryInterval time.Duration
+	// states whether the node can start as a single-node cluster when it fails to join an existing cluster
+	// In that case the node keeps discovering the peers in the background until it joins them
+	singleNodeStart bool
+	// specifies the interval at which the peers are discovered again and joined when they are not
+	// members of the cluster. This heals network partitions. Zero disables the rediscovery
+	rediscoveryInterval time.Duration
 	// specifies the discovery provider
 	provider discovery.Provider
 	// specifies the node client port
@@ -76,6 +88,45 @@ type Config struct {
 	// This has to be the same within the cluster to ensure smooth GCM authenticated data
 	// reference: https://en.wikipedia.org/wiki/Galois/Counter_Mode
 	cookie string
+	// specifies the connect interceptors
+	// These are applied to the node KVService handler and the built-in cluster client
+	interceptors []connect.Interceptor
+	// specifies the meter provider used to record the node metrics
+	meterProvider metric.MeterProvider
+	// specifies the http handler serving the metrics on the node /metrics endpoint
+	metricsHandler nethttp.Handler
+	// specifies the tracer provider used to trace the node operations
+	tracerProvider trace.TracerProvider
+	// specifies the node user-defined tags, for instance its zone, version or role
+	// The tags are gossiped to the peers as part of the node metadata
+	tags map[string]string
+	// specifies the zone the node runs in, for instance its availability zone or rack
+	zone string
+	// specifies the strategy placing the replicas of a key on the cluster members
+	placement PlacementStrategy
+	// specifies the http server timeouts
+	serverReadTimeout       time.Duration
+	serverReadHeaderTimeout time.Duration
+	serverWriteTimeout      time.Duration
+	serverIdleTimeout       time.Duration
+	// specifies the maximum size in bytes of a request body. Zero means no limit
+	maxRequestBodySize int64
+	// specifies the maximum number of concurrent HTTP/2 streams per connection.
+	// Zero means the HTTP/2 default
+	maxConcurrentStreams uint32
+	// specifies the storage limits of the node local state. Zero means no limit
+	maxKeySize   int
+	maxValueSize int
+	maxEntries   int
+	maxTotalSize int64
+	// specifies how the node makes room when a write exceeds the storage limits
+	evictionPolicy EvictionPolicy
+	// specifies the size in bytes of the chunks of the node local state transferred to the peers
+	stateChunkSize int
+	// states whether the node hands off its entries to its peers before leaving the cluster when it stops
+	drainOnStop bool
+	// specifies how long a deleted key is remembered to supersede its previous value on every node
+	tombstoneRetention time.Duration
 }
 
 // enforce compilation error
@@ -85,13 +136,21 @@ var _ validation.Validator = (*Config)(nil)
 // with the required default values
 func NewConfig() *Config {
 	return &Config{
-		host:              "0.0.0.0",
-		maxJoinAttempts:   5,
-		joinRetryInterval: time.Second,
-		shutdownTimeout:   3 * time.Second,
-		syncInterval:      time.Minute,
-		logger:            log.New(log.ErrorLevel, os.Stderr),
-		readTimeout:       time.Second,
+		host:                    "0.0.0.0",
+		maxJoinAttempts:         5,
+		joinRetryInterval:       time.Second,
+		rediscoveryInterval:     30 * time.Second,
+		shutdownTimeout:         3 * time.Second,
+		syncInterval:            time.Minute,
+		logger:                  log.New(log.ErrorLevel, os.Stderr),
+		readTimeout:             time.Second,
+		placement:               NewZoneAwarePlacement(),
+		serverReadTimeout:       3 * time.Second,
+		serverReadHeaderTimeout: time.Second,
+		serverWriteTimeout:      time.Second,
+		serverIdleTimeout:       1200 * time.Second,
+		stateChunkSize:          defaultStateChunkSize,
+		tombstoneRetention:      time.Hour,
 	}
 }
 
@@ -137,6 +196,147 @@ func (config *Config) WithJoinRetryInterval(interval time.Duration) *Config {
 	return config
 }
 
+// WithSingleNodeStart allows the node to start as a single-node cluster when it fails to discover or join its peers
+// after the max join attempts. The node then keeps discovering the peers in the background, at every
+// join retry interval, until it joins them.
+func (config *Config) WithSingleNodeStart(enabled bool) *Config {
+	config.singleNodeStart = enabled
+	return config
+}
+
+// WithRediscoveryInterval sets the interval at which the node discovers its peers again and joins
+// the ones that are not members of the cluster. This lets the cluster heal after a network partition or
+// converge when the peers addresses change, for instance after a rolling restart. Zero disables the rediscovery.
+func (config *Config) WithRediscoveryInterval(interval time.Duration) *Config {
+	config.rediscoveryInterval = interval
+	return config
+}
+
+// WithTags sets the node user-defined tags, for instance its zone, version or role.
+// The tags are gossiped to the peers and can be updated at runtime with Node.SetTags
+func (config *Config) WithTags(tags map[string]string) *Config {
+	config.tags = make(map[string]string, len(tags))
+	for key, value := range tags {
+		config.tags[key] = value
+	}
+	return config
+}
+
+// WithZone sets the zone the node runs in, for instance its availability zone or rack.
+// The zone is gossiped to the peers and used to spread the replicas of a key across distinct zones
+func (config *Config) WithZone(zone string) *Config {
+	config.zone = zone
+	return config
+}
+
+// WithPlacementStrategy sets the strategy placing the replicas of a key on the cluster members.
+// Defaults to the zone-aware rendezvous hashing placement. See NewZoneAwarePlacement
+func (config *Config) WithPlacementStrategy(strategy PlacementStrategy) *Config {
+	config.placement = strategy
+	return config
+}
+
+// WithServerReadTimeout sets the maximum duration for the node http server to read an entire request, including the body.
+// Defaults to 3 seconds
+func (config *Config) WithServerReadTimeout(timeout time.Duration) *Config {
+	config.serverReadTimeout = timeout
+	return config
+}
+
+// WithServerReadHeaderTimeout sets the maximum duration for the node http server to read the request headers.
+// It cannot exceed the server read timeout. Defaults to 1 second
+func (config *Config) WithServerReadHeaderTimeout(timeout time.Duration) *Config {
+	config.serverReadHeaderTimeout = timeout
+	return config
+}
+
+// WithServerWriteTimeout sets the maximum duration for the node http server to write a response.
+// This needs to be increased when listing a large number of entries. Defaults to 1 second
+func (config *Config) WithServerWriteTimeout(timeout time.Duration) *Config {
+	config.serverWriteTimeout = timeout
+	return config
+}
+
+// WithServerIdleTimeout sets the maximum duration for the node http server to wait for the next request
+// on a kept-alive connection. Defaults to 20 minutes
+func (config *Config) WithServerIdleTimeout(timeout time.Duration) *Config {
+	config.serverIdleTimeout = timeout
+	return config
+}
+
+// WithMaxRequestBodySize sets the maximum size in bytes of a request body accepted by the node http server.
+// Zero means no limit which is the default
+func (config *Config) WithMaxRequestBodySize(size int64) *Config {
+	config.maxRequestBodySize = size
+	return config
+}
+
+// WithMaxConcurrentStreams sets the maximum number of concurrent HTTP/2 streams per connection
+// accepted by the node http server. Zero means the HTTP/2 default which is the default
+func (config *Config) WithMaxConcurrentStreams(streams uint32) *Config {
+	config.maxConcurrentStreams = streams
+	return config
+}
+
+// WithMaxKeySize sets the maximum size in bytes of a key.
+// Zero means no limit which is the default
+func (config *Config) WithMaxKeySize(size int) *Config {
+	config.maxKeySize = size
+	return config
+}
+
+// WithMaxValueSize sets the maximum size in bytes of a value.
+// Zero means no limit which is the default
+func (config *Config) WithMaxValueSize(size int) *Config {
+	config.maxValueSize = size
+	return config
+}
+
+// WithMaxEntries sets the maximum number of entries of the node local state.
+// Zero means no limit which is the default
+func (config *Config) WithMaxEntries(entries int) *Config {
+	config.maxEntries = entries
+	return config
+}
+
+// WithMaxTotalSize sets the maximum size in bytes of the keys and values of the node local state.
+// Zero means no limit which is the default
+func (config *Config) WithMaxTotalSize(size int64) *Config {
+	config.maxTotalSize = size
+	return config
+}
+
+// WithEvictionPolicy sets the policy used to evict entries from the node local state when a write
+// exceeds the maximum number of entries or the maximum total size. This requires at least one of these limits.
+// Every evicted entry is picked among a random sample of the local state. Defaults to NoEviction which rejects the write
+func (config *Config) WithEvictionPolicy(policy EvictionPolicy) *Config {
+	config.evictionPolicy = policy
+	return config
+}
+
+// WithStateChunkSize sets the size in bytes of the chunks of the node local state streamed to the peers
+// when they transfer it. The push/pull only exchanges the digests of the nodes states. Defaults to 1MB
+func (config *Config) WithStateChunkSize(size int) *Config {
+	config.stateChunkSize = size
+	return config
+}
+
+// WithDrainOnStop makes Node.Stop hand off the node local entries to its peers before leaving the cluster,
+// as Node.Drain does. The handoff is bounded by the shutdown timeout and the node still stops when it fails
+func (config *Config) WithDrainOnStop(enabled bool) *Config {
+	config.drainOnStop = enabled
+	return config
+}
+
+// WithTombstoneRetention sets how long a deleted key is remembered by the node that received the delete.
+// During that time the delete supersedes the previous value of the key on every node. It needs to exceed
+// the time the cluster takes to converge, bearing in mind the sync interval. The deleted keys are purged
+// at every retention, whether the cleaner job is enabled or not. Defaults to 1 hour
+func (config *Config) WithTombstoneRetention(retention time.Duration) *Config {
+	config.tombstoneRetention = retention
+	return config
+}
+
 // WithHost specifies the config host
 func (config *Config) WithHost(host string) *Config {
 	config.host = host
@@ -150,7 +350,8 @@ func (config *Config) WithSyncInterval(interval time.Duration) *Config {
 }
 
 // WithReadTimeout sets the Node read timeout.
-// This timeout specifies the timeout of a data retrieval
+// This timeout specifies the timeout of a data retrieval. It is enforced by the Get and List handlers
+// which fail with a DeadlineExceeded error when the retrieval does not complete in time. Zero disables it
 func (config *Config) WithReadTimeout(timeout time.Duration) *Config {
 	config.readTimeout = timeout
 	return config
@@ -179,6 +380,36 @@ func (config *Config) WithEncryption(cookie string, secretKeys []string) *Config
 	return config
 }
 
+// WithInterceptors sets the connect interceptors.
+// The interceptors are hooked to the node KVService handler and the built-in cluster client.
+// This allows one to add authentication, tracing, retries or logging to the node.
+func (config *Config) WithInterceptors(interceptors ...connect.Interceptor) *Config {
+	config.interceptors = append(config.interceptors, interceptors...)
+	return config
+}
+
+// WithMetrics sets the meter provider used to record the node metrics.
+// The node records the KVService calls, the state sizes, the push/pull exchanges,
+// the cleaner and store evictions and the number of cluster members.
+func (config *Config) WithMetrics(provider metric.MeterProvider) *Config {
+	config.meterProvider = provider
+	return config
+}
+
+// WithMetricsHandler sets the http handler to serve on the node /metrics endpoint.
+// For instance one can set the handler of a Prometheus exporter registered with the meter provider.
+func (config *Config) WithMetricsHandler(handler nethttp.Handler) *Config {
+	config.metricsHandler = handler
+	return config
+}
+
+// WithTracing sets the tracer provider used to trace the node operations.
+// The node traces the KVService calls, the delegate operations and the merge of the peers state.
+func (config *Config) WithTracing(provider trace.TracerProvider) *Config {
+	config.tracerProvider = provider
+	return config
+}
+
 // Validate implements validation.Validator.
 func (config *Config) Validate() error {
 	return validation.
@@ -188,6 +419,23 @@ func (config *Config) Validate() error {
 		AddAssertion(config.shutdownTimeout > 0, "shutdown timeout is invalid").
 		AddAssertion(config.maxJoinAttempts > 0, "max join attempts is invalid").
 		AddAssertion(config.syncInterval > 0, "stateSync interval is invalid").
+		AddAssertion(config.rediscoveryInterval >= 0, "rediscovery interval is invalid").
+		AddAssertion(config.placement != nil, "placement strategy is not set").
+		AddAssertion(config.serverReadTimeout > 0, "server read timeout is invalid").
+		AddAssertion(config.serverReadHeaderTimeout > 0, "server read header timeout is invalid").
+		AddAssertion(config.serverReadHeaderTimeout <= config.serverReadTimeout, "server read header timeout exceeds the server read timeout").
+		AddAssertion(config.serverWriteTimeout > 0, "server write timeout is invalid").
+		AddAssertion(config.serverIdleTimeout > 0, "server idle timeout is invalid").
+		AddAssertion(config.maxRequestBodySize >= 0, "max request body size is invalid").
+		AddAssertion(config.maxKeySize >= 0, "max key size is invalid").
+		AddAssertion(config.maxValueSize >= 0, "max value size is invalid").
+		AddAssertion(config.maxEntries >= 0, "max entries is invalid").
+		AddAssertion(config.maxTotalSize >= 0, "max total size is invalid").
+		AddAssertion(config.evictionPolicy >= NoEviction && config.evictionPolicy <= TTLFirst, "eviction policy is invalid").
+		AddAssertion(config.evictionPolicy == NoEviction || config.maxEntries > 0 || config.maxTotalSize > 0,
+			"eviction policy requires max entries or max total size").
+		AddAssertion(config.stateChunkSize > 0, "state chunk size is invalid").
+		AddAssertion(config.tombstoneRetention > 0, "tombstone retention is invalid").
 		AddValidator(validation.NewEmptyStringValidator("host", config.host)).
 		AddValidator(validation.NewConditionalValidator(len(config.secretKeys) != 0,
 			validation.NewEmptyStringValidator("config.cookie", config.cookie))).
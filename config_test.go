@@ -201,4 +201,134 @@ func TestConfig(t *testing.T) {
 		assert.Error(t, err)
 		assert.EqualError(t, err, "max join attempts is invalid")
 	})
+	t.Run("With invalid rediscovery interval", func(t *testing.T) {
+		discovery := new(mocks.Provider)
+		config := NewConfig().
+			WithPort(1234).
+			WithDiscoveryPort(1235).
+			WithDiscoveryProvider(discovery).
+			WithHost("127.0.0.1").
+			WithLogger(log.DiscardLogger).
+			WithSyncInterval(time.Second).
+			WithJoinRetryInterval(time.Second).
+			WithShutdownTimeout(time.Second).
+			WithRediscoveryInterval(-1).
+			WithReadTimeout(time.Second)
+		err := config.Validate()
+		assert.Error(t, err)
+		assert.EqualError(t, err, "rediscovery interval is invalid")
+	})
+	t.Run("With invalid server settings", func(t *testing.T) {
+		testCases := []struct {
+			name     string
+			config   *Config
+			expected string
+		}{
+			{
+				name:     "read timeout",
+				config:   NewConfig().WithServerReadTimeout(0),
+				expected: "server read timeout is invalid",
+			},
+			{
+				name:     "read header timeout",
+				config:   NewConfig().WithServerReadHeaderTimeout(-1),
+				expected: "server read header timeout is invalid",
+			},
+			{
+				name:     "read header timeout exceeding the read timeout",
+				config:   NewConfig().WithServerReadTimeout(time.Second).WithServerReadHeaderTimeout(2 * time.Second),
+				expected: "server read header timeout exceeds the server read timeout",
+			},
+			{
+				name:     "write timeout",
+				config:   NewConfig().WithServerWriteTimeout(0),
+				expected: "server write timeout is invalid",
+			},
+			{
+				name:     "idle timeout",
+				config:   NewConfig().WithServerIdleTimeout(0),
+				expected: "server idle timeout is invalid",
+			},
+			{
+				name:     "max request body size",
+				config:   NewConfig().WithMaxRequestBodySize(-1),
+				expected: "max request body size is invalid",
+			},
+			{
+				name:     "max key size",
+				config:   NewConfig().WithMaxKeySize(-1),
+				expected: "max key size is invalid",
+			},
+			{
+				name:     "max value size",
+				config:   NewConfig().WithMaxValueSize(-1),
+				expected: "max value size is invalid",
+			},
+			{
+				name:     "max entries",
+				config:   NewConfig().WithMaxEntries(-1),
+				expected: "max entries is invalid",
+			},
+			{
+				name:     "max total size",
+				config:   NewConfig().WithMaxTotalSize(-1),
+				expected: "max total size is invalid",
+			},
+			{
+				name:     "eviction policy",
+				config:   NewConfig().WithEvictionPolicy(EvictionPolicy(10)),
+				expected: "eviction policy is invalid",
+			},
+			{
+				name:     "eviction policy without limits",
+				config:   NewConfig().WithEvictionPolicy(LFU),
+				expected: "eviction policy requires max entries or max total size",
+			},
+			{
+				name:     "state chunk size",
+				config:   NewConfig().WithStateChunkSize(0),
+				expected: "state chunk size is invalid",
+			},
+			{
+				name:     "tombstone retention",
+				config:   NewConfig().WithTombstoneRetention(0),
+				expected: "tombstone retention is invalid",
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				config := tc.config.
+					WithPort(1234).
+					WithDiscoveryPort(1235).
+					WithDiscoveryProvider(new(mocks.Provider)).
+					WithHost("127.0.0.1").
+					WithLogger(log.DiscardLogger)
+				assert.EqualError(t, config.Validate(), tc.expected)
+			})
+		}
+	})
+	t.Run("With server settings", func(t *testing.T) {
+		config := NewConfig().
+			WithPort(1234).
+			WithDiscoveryPort(1235).
+			WithDiscoveryProvider(new(mocks.Provider)).
+			WithHost("127.0.0.1").
+			WithLogger(log.DiscardLogger).
+			WithServerReadTimeout(10 * time.Second).
+			WithServerReadHeaderTimeout(2 * time.Second).
+			WithServerWriteTimeout(30 * time.Second).
+			WithServerIdleTimeout(time.Minute).
+			WithMaxRequestBodySize(1 << 20).
+			WithMaxConcurrentStreams(100).
+			WithMaxKeySize(256).
+			WithMaxValueSize(1 << 16).
+			WithMaxEntries(1000).
+			WithMaxTotalSize(1 << 24).
+			WithEvictionPolicy(TTLFirst).
+			WithStateChunkSize(64 << 10).
+			WithDrainOnStop(true).
+			WithTombstoneRetention(10 * time.Minute)
+		assert.NoError(t, config.Validate())
+	})
 }
@@ -25,10 +25,15 @@
 package gokv
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"sync"
 	"time"
 
 	"github.com/hashicorp/memberlist"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/atomic"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/durationpb"
 	"google.golang.org/protobuf/types/known/timestamppb"
@@ -36,21 +41,41 @@ import (
 	"github.com/tochemey/gokv/internal/internalpb"
 )
 
+// leaveMessage prefixes the user message sent by a node to its peers
+// before gracefully leaving the cluster. It is followed by the node name
+const leaveMessage byte = 1
+
+var (
+	// errInvalidPeerState is recorded when a peer state does not carry its node id
+	errInvalidPeerState = errors.New("invalid peer state")
+	// errStalePeerState is recorded when a peer state is older than the merged one
+	errStalePeerState = errors.New("stale peer state")
+)
+
 // delegate defines the given node finite state machine
 // in the cluster
 type delegate struct {
-	sync.RWMutex
 	self string
 
 	// node metadata shared in the cluster
 	// for instance the IP discoveryAddress of the node, the name of the node
 	// relevant information that can be known by the other peers in the cluster
 	nodeMeta *internalpb.NodeMeta
+	metaLock sync.RWMutex
+	// member describes the node. It is replaced with the node metadata so that it is read without copying the metadata
+	member *atomic.Pointer[Member]
 
-	// localState holds the node local state
+	// local holds the node local state
 	// this will be replicated on other peer nodes
 	// via the gossip protocol
-	localState *internalpb.NodeState
+	local *localStore
+
+	// limits defines the local state storage limits
+	limits storageLimits
+	// capacityLock serializes the writes when storage limits are set
+	capacityLock sync.Mutex
+	// tombstoneRetention defines how long a removed entry supersedes its key in the cluster
+	tombstoneRetention time.Duration
 
 	// peersState holds all the peers state
 	// this will be used when merging other node state
@@ -58,6 +83,93 @@ type delegate struct {
 	// internalpb.NodeState and try to find out whether the given entry exists in its peer
 	// state and add it.
 	peersState *internalpb.PeersState
+
+	// index maps every key of the peers states to its winning entry.
+	// It is updated incrementally on every merge
+	index map[string]*indexEntry
+
+	// lastSyncs holds the last time each peer state has been merged
+	lastSyncs map[string]time.Time
+
+	// peersSizes holds the size in bytes of every merged peer state
+	peersSizes map[string]int
+	// peersEntries and peersBytes track the number of entries and the size of the merged peers states
+	peersEntries *atomic.Int64
+	peersBytes   *atomic.Int64
+
+	// leaving holds the peers that have announced a graceful leave
+	leaving map[string]struct{}
+
+	// peersLock guards the peers state, the index, the last syncs, the peers sizes and the leave announcements
+	peersLock sync.RWMutex
+
+	// onChanges is called with the changes of a peer state found when merging it
+	onChanges func(changes []*keyChange)
+
+	// fetchState transfers the state of a peer whose digest is newer than the merged state
+	fetchState stateFetcher
+	// transfers holds the peers whose state is being transferred. It is guarded by the peers lock
+	transfers map[string]struct{}
+	// transfersCtx is canceled to abort the state transfers, the fetched and the served ones, when the node stops
+	transfersCtx  context.Context
+	stopTransfers context.CancelFunc
+
+	// metrics holds the push/pull instruments
+	metrics *metrics
+	// tracer is used to trace the delegate operations
+	tracer trace.Tracer
+}
+
+// stateFetcher transfers the state of the peer described by the given digest
+type stateFetcher func(ctx context.Context, digest *internalpb.NodeDigest) (*internalpb.NodeState, error)
+
+// versioned defines a node state version
+type versioned interface {
+	GetGeneration() uint64
+	GetVersion() uint64
+}
+
+// storageLimits defines the local state storage limits. Zero means no limit
+type storageLimits struct {
+	maxEntries   int
+	maxTotalSize int64
+	policy       EvictionPolicy
+}
+
+// enabled returns true when a limit is set
+func (limits storageLimits) enabled() bool {
+	return limits.maxEntries > 0 || limits.maxTotalSize > 0
+}
+
+// delegateStats defines the delegate state statistics
+type delegateStats struct {
+	localEntries int
+	localBytes   int
+	peersEntries int
+	peersBytes   int
+}
+
+// putResult defines the outcome of a Put
+type putResult struct {
+	// entry is the new entry of the key
+	entry *internalpb.Entry
+	// updated states whether an existing key has been updated
+	updated bool
+	// evicted holds the keys evicted to make room for the new entry
+	evicted []string
+}
+
+// indexEntry defines the winning entry of a key among the peers states
+type indexEntry struct {
+	nodeID string
+	entry  *internalpb.Entry
+}
+
+// keyChange defines a change of a key
+type keyChange struct {
+	key string
+	// entry is the new entry of the key. It is nil when the key has been removed
+	entry *internalpb.Entry
 }
 
 // enforce compilation error
@@ -68,10 +180,10 @@ var _ memberlist.Delegate = (*delegate)(nil)
 // the given byte size. This metadata is available in the Node structure.
 // nolint
 func (fsm *delegate) NodeMeta(limit int) []byte {
-	fsm.Lock()
+	fsm.metaLock.RLock()
 	// no need to check the error
 	bytea, _ := proto.Marshal(fsm.nodeMeta)
-	fsm.Unlock()
+	fsm.metaLock.RUnlock()
 	return bytea
 }
 
@@ -80,7 +192,13 @@ func (fsm *delegate) NodeMeta(limit int) []byte {
 // so would block the entire UDP packet receive loop. Additionally, the byte
 // slice may be modified after the call returns, so it should be copied if needed
 // nolint
-func (fsm *delegate) NotifyMsg(bytes []byte) {}
+func (fsm *delegate) NotifyMsg(bytes []byte) {
+	if len(bytes) > 1 && bytes[0] == leaveMessage {
+		fsm.peersLock.Lock()
+		fsm.leaving[string(bytes[1:])] = struct{}{}
+		fsm.peersLock.Unlock()
+	}
+}
 
 // GetBroadcasts is called when user data messages can be broadcast.
 // It can return a list of buffers to send. Each buffer should assume an
@@ -97,11 +215,13 @@ func (fsm *delegate) GetBroadcasts(overhead, limit int) [][]byte {
 // the remote side in addition to the membership information. Any
 // data can be sent here. See MergeRemoteState as well. The `join`
 // boolean indicates this is for a join instead of a push/pull.
+// Only the digest of the local state is sent, the peers transfer the state itself
+// over the node KVService when their copy is outdated
 // nolint
 func (fsm *delegate) LocalState(join bool) []byte {
-	fsm.Lock()
-	bytea, _ := proto.Marshal(fsm.localState)
-	fsm.Unlock()
+	start := time.Now()
+	bytea, _ := proto.Marshal(fsm.digest())
+	fsm.metrics.recordPushPull(pushOperation, len(bytea), start)
 	return bytea
 }
 
@@ -109,167 +229,728 @@ func (fsm *delegate) LocalState(join bool) []byte {
 // delegate received from the remote side and is the result of the
 // remote side's LocalState call. The 'join'
 // boolean indicates this is for a join instead of a push/pull.
+// A digest that cannot be decoded or that is older than the merged state is rejected,
+// a newer one triggers the transfer of the peer state in the background
 // nolint
 func (fsm *delegate) MergeRemoteState(buf []byte, join bool) {
-	fsm.Lock()
-	incomingState := new(internalpb.NodeState)
-	_ = proto.Unmarshal(buf, incomingState)
-	incomingNodeID := incomingState.GetNodeId()
+	start := time.Now()
+	defer fsm.metrics.recordPushPull(pullOperation, len(buf), start)
+
+	_, span := fsm.tracer.Start(context.Background(), "delegate.MergeRemoteState",
+		trace.WithAttributes(nodeAttribute.String(fsm.self), joinAttribute.Bool(join)))
 
-	// override the existing peer state if already exists
-	fsm.peersState.GetRemoteStates()[incomingNodeID] = incomingState
-	fsm.Unlock()
+	var err error
+	defer func() {
+		endSpan(span, err)
+	}()
+
+	digest := new(internalpb.NodeDigest)
+	if err = proto.Unmarshal(buf, digest); err != nil {
+		fsm.metrics.recordRejectedState(invalidStateReason)
+		return
+	}
+
+	incomingNodeID := digest.GetNodeId()
+	if incomingNodeID == "" {
+		err = errInvalidPeerState
+		fsm.metrics.recordRejectedState(invalidStateReason)
+		return
+	}
+
+	span.SetAttributes(sourceNodeAttribute.String(incomingNodeID))
+
+	fsm.peersLock.Lock()
+	previousState, exists := fsm.peersState.GetRemoteStates()[incomingNodeID]
+	if exists && stale(digest, previousState) {
+		fsm.peersLock.Unlock()
+		err = errStalePeerState
+		fsm.metrics.recordRejectedState(staleStateReason)
+		return
+	}
+
+	if exists && sameVersion(digest, previousState) {
+		// the peer has not changed since the last merge
+		fsm.lastSyncs[incomingNodeID] = time.Now().UTC()
+		fsm.peersLock.Unlock()
+		return
+	}
+
+	if _, transferring := fsm.transfers[incomingNodeID]; transferring || fsm.fetchState == nil {
+		fsm.peersLock.Unlock()
+		return
+	}
+	fsm.transfers[incomingNodeID] = struct{}{}
+	fsm.peersLock.Unlock()
+
+	// a peer that has never changed its state has nothing to transfer
+	if digest.GetVersion() == 0 {
+		err = fsm.merge(&internalpb.NodeState{
+			NodeId:     incomingNodeID,
+			Entries:    make(map[string]*internalpb.Entry),
+			Generation: digest.GetGeneration(),
+		})
+		return
+	}
+
+	go fsm.transfer(digest)
+}
+
+// transfer fetches the state of the peer described by the given digest and merges it
+func (fsm *delegate) transfer(digest *internalpb.NodeDigest) {
+	start := time.Now()
+	ctx, span := fsm.tracer.Start(fsm.transfersCtx, "delegate.transfer",
+		trace.WithAttributes(nodeAttribute.String(fsm.self), sourceNodeAttribute.String(digest.GetNodeId())))
+
+	state, err := fsm.fetchState(ctx, digest)
+	if err == nil && state.GetNodeId() != digest.GetNodeId() {
+		err = errInvalidPeerState
+	}
+
+	if err != nil {
+		fsm.peersLock.Lock()
+		delete(fsm.transfers, digest.GetNodeId())
+		fsm.peersLock.Unlock()
+		endSpan(span, err)
+		return
+	}
+
+	fsm.metrics.recordPushPull(transferOperation, proto.Size(state), start)
+	span.SetAttributes(entriesAttribute.Int(len(state.GetEntries())))
+	endSpan(span, fsm.merge(state))
 }
 
-// Put adds the key/value to the node local state
-func (fsm *delegate) Put(key string, value []byte, expiration time.Duration) {
-	fsm.Lock()
-	localState := fsm.localState
-	newEntry := &internalpb.Entry{
+// merge replaces the merged state of a peer with the given state when it is not older.
+// The state is diffed against the merged one to update the index and notify the changes
+func (fsm *delegate) merge(state *internalpb.NodeState) error {
+	nodeID := state.GetNodeId()
+	size := proto.Size(state)
+
+	fsm.peersLock.Lock()
+	delete(fsm.transfers, nodeID)
+	previousState, exists := fsm.peersState.GetRemoteStates()[nodeID]
+	if exists && stale(state, previousState) {
+		fsm.peersLock.Unlock()
+		fsm.metrics.recordRejectedState(staleStateReason)
+		return errStalePeerState
+	}
+
+	fsm.lastSyncs[nodeID] = time.Now().UTC()
+	if exists && sameVersion(state, previousState) {
+		fsm.peersLock.Unlock()
+		return nil
+	}
+
+	fsm.peersState.GetRemoteStates()[nodeID] = state
+	fsm.peersEntries.Add(int64(len(state.GetEntries()) - len(previousState.GetEntries())))
+	fsm.peersBytes.Add(int64(size - fsm.peersSizes[nodeID]))
+	fsm.peersSizes[nodeID] = size
+	changes := diffStates(previousState, state)
+	fsm.updateIndex(nodeID, changes)
+	fsm.peersLock.Unlock()
+
+	fsm.dropSuperseded(changes)
+	if fsm.onChanges != nil && len(changes) > 0 {
+		fsm.onChanges(fsm.resolve(changes))
+	}
+	return nil
+}
+
+// dropSuperseded removes the local entries superseded by a more recent entry of a peer, including a removed one,
+// so that they are not served again once the peer entry is gone. An entry updated at the same time is kept
+func (fsm *delegate) dropSuperseded(changes []*keyChange) {
+	for _, change := range changes {
+		if change.entry == nil {
+			continue
+		}
+
+		local, exists := fsm.local.peek(change.key)
+		if exists && change.entry.GetLastUpdatedTime().AsTime().After(local.GetLastUpdatedTime().AsTime()) {
+			fsm.local.removeIf(change.key, local)
+		}
+	}
+}
+
+// resolve returns the given changes with the winning entry of their key as seen by the node.
+// The entry of a change is nil when the key has been removed or has expired
+func (fsm *delegate) resolve(changes []*keyChange) []*keyChange {
+	resolved := make([]*keyChange, 0, len(changes))
+	for _, change := range changes {
+		entry, _, exists := fsm.lookup(change.key, false)
+		if !exists || !visible(entry) {
+			entry = nil
+		}
+		resolved = append(resolved, &keyChange{key: change.key, entry: entry})
+	}
+	return resolved
+}
+
+// streamState sends the local state in chunks of about the given size in bytes.
+// A chunk is always sent, even when the local state is empty, to carry the state version
+func (fsm *delegate) streamState(chunkSize int, send func(chunk *internalpb.StreamStateResponse) error) error {
+	state := fsm.local.snapshot()
+	newChunk := func() *internalpb.StreamStateResponse {
+		return &internalpb.StreamStateResponse{
+			NodeId:     state.GetNodeId(),
+			Generation: state.GetGeneration(),
+			Version:    state.GetVersion(),
+		}
+	}
+
+	chunk := newChunk()
+	size := 0
+	for _, entry := range state.GetEntries() {
+		chunk.Entries = append(chunk.Entries, entry)
+		if size += proto.Size(entry); size < chunkSize {
+			continue
+		}
+
+		if err := send(chunk); err != nil {
+			return err
+		}
+		chunk = newChunk()
+		size = 0
+	}
+
+	if len(chunk.GetEntries()) == 0 && len(state.GetEntries()) > 0 {
+		return nil
+	}
+	return send(chunk)
+}
+
+// digest returns the digest of the local state
+func (fsm *delegate) digest() *internalpb.NodeDigest {
+	fsm.metaLock.RLock()
+	host, port := fsm.nodeMeta.GetHost(), fsm.nodeMeta.GetPort()
+	fsm.metaLock.RUnlock()
+
+	return &internalpb.NodeDigest{
+		NodeId:     fsm.self,
+		Generation: fsm.local.generation,
+		Version:    fsm.local.version.Load(),
+		Host:       host,
+		Port:       port,
+	}
+}
+
+// Put adds the key/value to the node local state.
+// When storage limits are set, the writes are serialized and room is made for the new entry
+// according to the eviction policy. It returns ErrTooManyEntries or ErrStoreFull when the entry does not fit
+func (fsm *delegate) Put(ctx context.Context, key string, value []byte, expiration time.Duration) (*putResult, error) {
+	_, span := fsm.tracer.Start(ctx, "delegate.Put", trace.WithAttributes(keyAttribute.String(key)))
+	defer span.End()
+
+	return fsm.store(&internalpb.Entry{
 		Key:             key,
 		Value:           value,
 		LastUpdatedTime: timestamppb.New(time.Now().UTC()),
 		Expiry:          setExpiry(expiration),
+	})
+}
+
+// Adopt adds the given entry handed off by a draining peer to the node local state.
+// The entry keeps its last updated time and expiry, the last updated time being clamped to the current time
+// so that an entry stamped in the future does not supersede the later writes of its key.
+// It is skipped, and a nil result is returned, when it has expired or when the local state holds a more recent entry of its key
+func (fsm *delegate) Adopt(ctx context.Context, entry *internalpb.Entry) (*putResult, error) {
+	_, span := fsm.tracer.Start(ctx, "delegate.Adopt", trace.WithAttributes(keyAttribute.String(entry.GetKey())))
+	defer span.End()
+
+	if now := time.Now().UTC(); entry.GetLastUpdatedTime().AsTime().After(now) {
+		entry = proto.Clone(entry).(*internalpb.Entry)
+		entry.LastUpdatedTime = timestamppb.New(now)
+	}
+
+	if expired(entry) {
+		return nil, nil
+	}
+
+	if current, exists := fsm.local.peek(entry.GetKey()); exists &&
+		!entry.GetLastUpdatedTime().AsTime().After(current.GetLastUpdatedTime().AsTime()) {
+		return nil, nil
+	}
+	return fsm.store(entry)
+}
+
+// store adds the given entry to the node local state.
+// When storage limits are set, the writes are serialized and room is made for the new entry
+// according to the eviction policy. A removed entry takes no room
+func (fsm *delegate) store(entry *internalpb.Entry) (*putResult, error) {
+	previous, _, exists := fsm.lookup(entry.GetKey(), false)
+	result := &putResult{entry: entry, updated: exists && visible(previous)}
+	if fsm.limits.enabled() {
+		fsm.capacityLock.Lock()
+		defer fsm.capacityLock.Unlock()
+
+		if !entry.GetArchived() {
+			var err error
+			if result.evicted, err = fsm.reserve(entry.GetKey(), entry.GetValue()); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	fsm.local.put(entry)
+	return result, nil
+}
+
+// reserve makes room in the local state for the given key/value pair and returns the evicted keys.
+// The replaced key does not count against the limits. The caller must hold the capacity lock
+func (fsm *delegate) reserve(key string, value []byte) ([]string, error) {
+	size := entrySize(key, value)
+	count := fsm.local.len() + 1
+	total := fsm.local.bytes() + size
+	if previous, exists := fsm.local.peek(key); exists && !previous.GetArchived() {
+		count--
+		total -= entrySize(key, previous.GetValue())
+	}
+
+	limits := fsm.limits
+	fits := func() bool {
+		return (limits.maxEntries <= 0 || count <= int64(limits.maxEntries)) &&
+			(limits.maxTotalSize <= 0 || total <= limits.maxTotalSize)
+	}
+
+	if fits() {
+		return nil, nil
+	}
+
+	if limits.policy == NoEviction {
+		if limits.maxEntries > 0 && count > int64(limits.maxEntries) {
+			return nil, ErrTooManyEntries
+		}
+		return nil, ErrStoreFull
+	}
+
+	// nothing is evicted when the key/value pair alone exceeds the maximum total size
+	if limits.maxTotalSize > 0 && size > limits.maxTotalSize {
+		return nil, ErrStoreFull
 	}
-	localState.GetEntries()[key] = newEntry
-	fsm.Unlock()
+
+	// the entry to evict is the first one of a sample ordered by the policy
+	var evicted []string
+	for !fits() {
+		candidates := fsm.local.sample(key, evictionSamples)
+		if len(candidates) == 0 {
+			// the sampled entries were all removed ones
+			candidates = fsm.local.candidates(key)
+		}
+
+		if len(candidates) == 0 {
+			break
+		}
+
+		// the evicted entry is replaced by a removed one so that the peers holding an older entry of its key drop it
+		sortCandidates(candidates, limits.policy)
+		if candidate := candidates[0]; fsm.local.archive(fsm.tombstone(candidate.key)) {
+			evicted = append(evicted, candidate.key)
+			count--
+			total -= candidate.size
+		}
+	}
+	return evicted, nil
 }
 
 // Get returns the value of the given key
 // This can return a false negative meaning that the key may exist but at the time of checking it
 // is having yet to be replicated in the cluster
-func (fsm *delegate) Get(key string) (*internalpb.Entry, error) {
-	fsm.RLock()
-	localState := fsm.localState
-
-	// first let us lookup our local state and see whether the given is in there
-	// if exists return it otherwise check the peer state maybe a node has it
-	if entry, exists := localState.GetEntries()[key]; exists {
-		fsm.RUnlock()
-		if expired(entry) {
-			return nil, ErrKeyNotFound
-		}
-		return entry, nil
+func (fsm *delegate) Get(ctx context.Context, key string) (*internalpb.Entry, error) {
+	_, span := fsm.tracer.Start(ctx, "delegate.Get", trace.WithAttributes(keyAttribute.String(key)))
+	entry, source, err := fsm.get(key)
+	if err == nil {
+		span.SetAttributes(hitAttributes(fsm.self, source)...)
 	}
+	endSpan(span, err)
+	return entry, err
+}
 
-	// this node does not have the given, let us check our current peer states
-	peerStates := fsm.peersState.GetRemoteStates()
-	for _, peerState := range peerStates {
-		if entry, exists := peerState.GetEntries()[key]; exists {
-			fsm.RUnlock()
-			if expired(entry) {
-				return nil, ErrKeyNotFound
-			}
-			return entry, nil
-		}
+// GetWithMetadata returns the value of the given key along with the metadata describing its source and freshness
+func (fsm *delegate) GetWithMetadata(ctx context.Context, key string) (*internalpb.Entry, *internalpb.EntryMetadata, error) {
+	_, span := fsm.tracer.Start(ctx, "delegate.GetWithMetadata", trace.WithAttributes(keyAttribute.String(key)))
+	entry, source, err := fsm.get(key)
+	if err != nil {
+		endSpan(span, err)
+		return nil, nil, err
+	}
+
+	span.SetAttributes(hitAttributes(fsm.self, source)...)
+	endSpan(span, nil)
+	return entry, fsm.describe(entry, source), nil
+}
+
+// describe returns the metadata of the given entry read from the state of the given node
+func (fsm *delegate) describe(entry *internalpb.Entry, nodeID string) *internalpb.EntryMetadata {
+	if nodeID == fsm.self {
+		return fsm.metadata(entry, nodeID, fsm.local.version.Load(), time.Time{})
 	}
-	fsm.RUnlock()
-	return nil, ErrKeyNotFound
+
+	fsm.peersLock.RLock()
+	version := fsm.peersState.GetRemoteStates()[nodeID].GetVersion()
+	lastSync := fsm.lastSyncs[nodeID]
+	fsm.peersLock.RUnlock()
+	return fsm.metadata(entry, nodeID, version, lastSync)
 }
 
-// Delete deletes the given key from the cluster
-// One can only delete a key if the given node is the owner
-func (fsm *delegate) Delete(key string) {
-	fsm.Lock()
-	localState := fsm.localState
-	if _, exists := localState.GetEntries()[key]; exists {
-		delete(localState.GetEntries(), key)
-		fsm.Unlock()
-		return
+// metadata returns the metadata of the given entry read from the given version of the state of the given node
+// last merged at the given time. The time is zero for the node local state
+func (fsm *delegate) metadata(entry *internalpb.Entry, nodeID string, version uint64, lastSync time.Time) *internalpb.EntryMetadata {
+	metadata := &internalpb.EntryMetadata{
+		NodeId:          nodeID,
+		ServedBy:        fsm.self,
+		LastUpdatedTime: entry.GetLastUpdatedTime(),
+		Version:         version,
+	}
+
+	if deadline := expiresAt(entry); !deadline.IsZero() {
+		metadata.TtlRemaining = durationpb.New(max(time.Until(deadline), 0))
+	}
+
+	if !lastSync.IsZero() {
+		metadata.SinceLastSync = durationpb.New(time.Since(lastSync))
+	}
+	return metadata
+}
+
+// get returns the value of the given key and the node id of the state holding it
+func (fsm *delegate) get(key string) (*internalpb.Entry, string, error) {
+	entry, nodeID, exists := fsm.lookup(key, true)
+	if !exists || !visible(entry) {
+		return nil, "", ErrKeyNotFound
+	}
+	return entry, nodeID, nil
+}
+
+// lookup returns the winning entry of the given key among the local state and the peers states index
+// and the node id of the state holding it. The winning entry can be a removed or an expired one.
+// The access to the local entry is recorded when touch is set
+func (fsm *delegate) lookup(key string, touch bool) (*internalpb.Entry, string, bool) {
+	var (
+		entry  *internalpb.Entry
+		exists bool
+	)
+
+	if touch {
+		entry, exists = fsm.local.get(key)
+	} else {
+		entry, exists = fsm.local.peek(key)
+	}
+
+	fsm.peersLock.RLock()
+	winner, indexed := fsm.index[key]
+	fsm.peersLock.RUnlock()
+
+	switch {
+	case exists && (!indexed || !newer(winner.entry, winner.nodeID, entry, fsm.self)):
+		return entry, fsm.self, true
+	case indexed:
+		return winner.entry, winner.nodeID, true
+	default:
+		return nil, "", false
+	}
+}
+
+// Delete deletes the given key from the cluster whatever the node holding it.
+// A removed entry is stored in the local state to supersede the key on every node until the tombstone retention elapses.
+// It is stored even when the key is not known yet, since the node may not have merged the state holding it.
+// It returns true when the key existed
+func (fsm *delegate) Delete(ctx context.Context, key string) bool {
+	_, span := fsm.tracer.Start(ctx, "delegate.Delete", trace.WithAttributes(keyAttribute.String(key)))
+	defer span.End()
+
+	if fsm.limits.enabled() {
+		fsm.capacityLock.Lock()
+		defer fsm.capacityLock.Unlock()
+	}
+
+	previous, _, exists := fsm.lookup(key, false)
+	fsm.local.put(fsm.tombstone(key))
+	return exists && visible(previous)
+}
+
+// tombstone returns a removed entry of the given key kept for the tombstone retention
+func (fsm *delegate) tombstone(key string) *internalpb.Entry {
+	return &internalpb.Entry{
+		Key:             key,
+		Archived:        proto.Bool(true),
+		LastUpdatedTime: timestamppb.New(time.Now().UTC()),
+		Expiry:          setExpiry(fsm.tombstoneRetention),
 	}
-	fsm.Unlock()
 }
 
 // Exists checks whether a given exists
 // This can return a false negative meaning that the key may exist but at the time of checking it
 // is having yet to be replicated in the cluster
-func (fsm *delegate) Exists(key string) bool {
-	fsm.RLock()
-	localState := fsm.localState
-
-	// first let us lookup our local state and see whether the given is in there
-	// if exists return it otherwise check the peer state maybe a node has it
-	if entry, exists := localState.GetEntries()[key]; exists {
-		fsm.RUnlock()
-		return !expired(entry)
-	}
-
-	// this node does not have the given, let us check our current peer states
-	peerStates := fsm.peersState.GetRemoteStates()
-	for _, peerState := range peerStates {
-		if entry, exists := peerState.GetEntries()[key]; exists {
-			fsm.RUnlock()
-			return !expired(entry)
-		}
-	}
-	fsm.RUnlock()
-	return false
+func (fsm *delegate) Exists(ctx context.Context, key string) bool {
+	_, span := fsm.tracer.Start(ctx, "delegate.Exists", trace.WithAttributes(keyAttribute.String(key)))
+	defer span.End()
+
+	entry, _, exists := fsm.lookup(key, false)
+	return exists && visible(entry)
 }
 
 // List returns the list of entries in the cluster
-// It returns a combined list of entries in the given node and its peers
+// It returns the winning entry of every key among the given node and its peers
 // at a given point in time.
-func (fsm *delegate) List() []*internalpb.Entry {
-	fsm.RLock()
-	localState := fsm.localState
-	var entries []*internalpb.Entry
-
-	for _, entry := range localState.GetEntries() {
-		if !expired(entry) {
-			entries = append(entries, entry)
+func (fsm *delegate) List(ctx context.Context) []*internalpb.Entry {
+	_, span := fsm.tracer.Start(ctx, "delegate.List")
+	defer span.End()
+
+	entries, _ := fsm.list(false)
+	span.SetAttributes(entriesAttribute.Int(len(entries)))
+	return entries
+}
+
+// ListWithMetadata returns the list of entries in the cluster along with their metadata by key
+func (fsm *delegate) ListWithMetadata(ctx context.Context) ([]*internalpb.Entry, map[string]*internalpb.EntryMetadata) {
+	_, span := fsm.tracer.Start(ctx, "delegate.ListWithMetadata")
+	defer span.End()
+
+	entries, metadata := fsm.list(true)
+	span.SetAttributes(entriesAttribute.Int(len(entries)))
+	return entries, metadata
+}
+
+// list returns the winning entry of every key among the given node and its peers
+// along with their metadata by key when requested
+func (fsm *delegate) list(withMetadata bool) ([]*internalpb.Entry, map[string]*internalpb.EntryMetadata) {
+	local := fsm.local.snapshot()
+	winners := make(map[string]*indexEntry, len(local.GetEntries()))
+	for key, entry := range local.GetEntries() {
+		winners[key] = &indexEntry{nodeID: fsm.self, entry: entry}
+	}
+
+	var (
+		versions  map[string]uint64
+		lastSyncs map[string]time.Time
+	)
+
+	fsm.peersLock.RLock()
+	for key, winner := range fsm.index {
+		if current, exists := winners[key]; !exists || newer(winner.entry, winner.nodeID, current.entry, current.nodeID) {
+			winners[key] = winner
 		}
 	}
 
-	for _, peerState := range fsm.peersState.GetRemoteStates() {
-		for _, peerState := range peerState.GetEntries() {
-			if !expired(peerState) {
-				entries = append(entries, peerState)
-			}
+	if withMetadata {
+		versions = make(map[string]uint64, len(fsm.lastSyncs)+1)
+		lastSyncs = make(map[string]time.Time, len(fsm.lastSyncs))
+		for nodeID, peerState := range fsm.peersState.GetRemoteStates() {
+			versions[nodeID] = peerState.GetVersion()
+			lastSyncs[nodeID] = fsm.lastSyncs[nodeID]
 		}
+		versions[fsm.self] = local.GetVersion()
 	}
+	fsm.peersLock.RUnlock()
 
-	fsm.RUnlock()
-	return entries
+	entries := make([]*internalpb.Entry, 0, len(winners))
+	var metadata map[string]*internalpb.EntryMetadata
+	if withMetadata {
+		metadata = make(map[string]*internalpb.EntryMetadata, len(winners))
+	}
+
+	for key, winner := range winners {
+		if !visible(winner.entry) {
+			continue
+		}
+
+		entries = append(entries, winner.entry)
+		if withMetadata {
+			metadata[key] = fsm.metadata(winner.entry, winner.nodeID, versions[winner.nodeID], lastSyncs[winner.nodeID])
+		}
+	}
+	return entries, metadata
 }
 
-// removeExpired removes all entries that are expired
-func (fsm *delegate) removeExpired() {
-	fsm.Lock()
-	localState := fsm.localState
-	for key, entry := range localState.GetEntries() {
-		if !expired(entry) {
-			delete(localState.GetEntries(), key)
+// updateIndex applies the changes of the given peer state to the index.
+// The caller must hold the peers lock and the peer state must already be merged
+func (fsm *delegate) updateIndex(nodeID string, changes []*keyChange) {
+	for _, change := range changes {
+		current, indexed := fsm.index[change.key]
+		switch {
+		case indexed && current.nodeID == nodeID:
+			// the winning entry has changed or has been removed
+			fsm.electWinner(change.key)
+		case change.entry != nil && (!indexed || newer(change.entry, nodeID, current.entry, current.nodeID)):
+			fsm.index[change.key] = &indexEntry{nodeID: nodeID, entry: change.entry}
 		}
 	}
-	fsm.Unlock()
+}
+
+// electWinner sets the winning entry of the given key among the peers states.
+// The caller must hold the peers lock
+func (fsm *delegate) electWinner(key string) {
+	var winner *indexEntry
+	for nodeID, peerState := range fsm.peersState.GetRemoteStates() {
+		entry, exists := peerState.GetEntries()[key]
+		if exists && (winner == nil || newer(entry, nodeID, winner.entry, winner.nodeID)) {
+			winner = &indexEntry{nodeID: nodeID, entry: entry}
+		}
+	}
+
+	if winner == nil {
+		delete(fsm.index, key)
+		return
+	}
+	fsm.index[key] = winner
+}
+
+// removeExpired removes all entries that are expired
+// and returns the number of removed entries
+func (fsm *delegate) removeExpired() int {
+	return fsm.local.removeExpired()
+}
+
+// removeTombstones removes the removed entries whose retention has elapsed
+// and returns the number of removed entries
+func (fsm *delegate) removeTombstones() int {
+	return fsm.local.removeTombstones()
+}
+
+// left returns true when the given peer has announced a graceful leave
+// and forgets the announcement
+func (fsm *delegate) left(name string) bool {
+	fsm.peersLock.Lock()
+	_, ok := fsm.leaving[name]
+	delete(fsm.leaving, name)
+	fsm.peersLock.Unlock()
+	return ok
+}
+
+// lastSyncTime returns the last time the given peer state has been merged.
+// It returns a zero time when the peer state has never been merged
+func (fsm *delegate) lastSyncTime(nodeID string) time.Time {
+	fsm.peersLock.RLock()
+	lastSync := fsm.lastSyncs[nodeID]
+	fsm.peersLock.RUnlock()
+	return lastSync
+}
+
+// synced returns true when at least one peer state has been merged
+func (fsm *delegate) synced() bool {
+	fsm.peersLock.RLock()
+	synced := len(fsm.lastSyncs) > 0
+	fsm.peersLock.RUnlock()
+	return synced
+}
+
+// meta returns a copy of the node metadata
+func (fsm *delegate) meta() *internalpb.NodeMeta {
+	fsm.metaLock.RLock()
+	meta := proto.Clone(fsm.nodeMeta).(*internalpb.NodeMeta)
+	fsm.metaLock.RUnlock()
+	return meta
+}
+
+// setTags replaces the node tags. It returns an error when the
+// resulting node metadata exceeds the memberlist limit
+func (fsm *delegate) setTags(tags map[string]string) error {
+	fsm.metaLock.Lock()
+	defer fsm.metaLock.Unlock()
+
+	meta := proto.Clone(fsm.nodeMeta).(*internalpb.NodeMeta)
+	meta.Tags = make(map[string]string, len(tags))
+	for key, value := range tags {
+		meta.Tags[key] = value
+	}
+
+	if size := proto.Size(meta); size > memberlist.MetaMaxSize {
+		return fmt.Errorf("%w: %d bytes exceeds %d bytes", ErrNodeMetaTooLarge, size, memberlist.MetaMaxSize)
+	}
+
+	fsm.nodeMeta = meta
+	fsm.member.Store(memberFromNodeMeta(meta))
+	return nil
+}
+
+// stats returns the number of entries and the size of both the local state and the peers state.
+// It reads the counters updated on every write and merge, hence it does not block them
+func (fsm *delegate) stats() *delegateStats {
+	return &delegateStats{
+		localEntries: int(fsm.local.len()),
+		localBytes:   int(fsm.local.bytes()),
+		peersEntries: int(fsm.peersEntries.Load()),
+		peersBytes:   int(fsm.peersBytes.Load()),
+	}
 }
 
 // newDelegate creates an instance of delegate
-func newDelegate(name string, meta *internalpb.NodeMeta) *delegate {
+func newDelegate(name string, meta *internalpb.NodeMeta, metrics *metrics, tracer trace.Tracer) *delegate {
+	transfersCtx, stopTransfers := context.WithCancel(context.Background())
 	return &delegate{
-		RWMutex:  sync.RWMutex{},
 		nodeMeta: meta,
+		member:   atomic.NewPointer(memberFromNodeMeta(meta)),
 		self:     name,
-		localState: &internalpb.NodeState{
-			NodeId:  name,
-			Entries: make(map[string]*internalpb.Entry, 10),
-		},
+		metrics:  metrics,
+		tracer:   tracer,
+		local:    newLocalStore(name),
 		peersState: &internalpb.PeersState{
 			RemoteStates: make(map[string]*internalpb.NodeState, 100),
 		},
+		index:         make(map[string]*indexEntry),
+		lastSyncs:     make(map[string]time.Time, 100),
+		peersSizes:    make(map[string]int, 100),
+		peersEntries:  atomic.NewInt64(0),
+		peersBytes:    atomic.NewInt64(0),
+		leaving:       make(map[string]struct{}),
+		transfers:     make(map[string]struct{}),
+		transfersCtx:  transfersCtx,
+		stopTransfers: stopTransfers,
 	}
 }
 
+// newer returns true when the entry held by the given node wins over the other one.
+// The latest updated entry wins and the lowest node id breaks the ties
+func newer(entry *internalpb.Entry, nodeID string, other *internalpb.Entry, otherNodeID string) bool {
+	updated := entry.GetLastUpdatedTime().AsTime()
+	otherUpdated := other.GetLastUpdatedTime().AsTime()
+	if !updated.Equal(otherUpdated) {
+		return updated.After(otherUpdated)
+	}
+	return nodeID < otherNodeID
+}
+
+// diffStates returns the keys added, updated or removed between the previous and the current state of a node
+func diffStates(previous, current *internalpb.NodeState) []*keyChange {
+	var changes []*keyChange
+	for key, entry := range current.GetEntries() {
+		old, exists := previous.GetEntries()[key]
+		if !exists || !old.GetLastUpdatedTime().AsTime().Equal(entry.GetLastUpdatedTime().AsTime()) {
+			changes = append(changes, &keyChange{key: key, entry: entry})
+		}
+	}
+
+	for key := range previous.GetEntries() {
+		if _, exists := current.GetEntries()[key]; !exists {
+			changes = append(changes, &keyChange{key: key})
+		}
+	}
+	return changes
+}
+
+// stale returns true when the given state is older than the other state of the same node
+func stale(state, other versioned) bool {
+	if state.GetGeneration() != other.GetGeneration() {
+		return state.GetGeneration() < other.GetGeneration()
+	}
+	return state.GetVersion() < other.GetVersion()
+}
+
+// sameVersion returns true when both states of the same node carry the same version
+func sameVersion(state, other versioned) bool {
+	return state.GetGeneration() == other.GetGeneration() && state.GetVersion() == other.GetVersion()
+}
+
+// visible returns true when the entry has neither been removed nor expired
+func visible(entry *internalpb.Entry) bool {
+	return !entry.GetArchived() && !expired(entry)
+}
+
 // expired returns true if the item has expired.
 func expired(entry *internalpb.Entry) bool {
 	if entry.GetExpiry() == nil {
 		return false
 	}
-	expiration := entry.GetLastUpdatedTime().AsTime().Unix()
-	if expiration <= 0 {
-		return false
-	}
-	return time.Now().UTC().Unix() > expiration
+	expiration := entry.GetLastUpdatedTime().AsTime().Add(entry.GetExpiry().AsDuration())
+	return time.Now().UTC().After(expiration)
 }
 
 // setExpiry sets the expiry time
@@ -0,0 +1,333 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2024 Arsene Tochemey Gandote
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package gokv
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/tochemey/gokv/internal/internalpb"
+)
+
+func TestMergeRemoteState(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC()
+	newTestDelegate := func() *delegate {
+		return newDelegate("node", new(internalpb.NodeMeta), noopMetrics(), newTracer(nil))
+	}
+	versions := make(map[string]uint64)
+	merge := func(t *testing.T, fsm *delegate, nodeID string, entries map[string]time.Time) {
+		versions[nodeID]++
+		state := &internalpb.NodeState{
+			NodeId:     nodeID,
+			Entries:    make(map[string]*internalpb.Entry, len(entries)),
+			Generation: 1,
+			Version:    versions[nodeID],
+		}
+		for key, updated := range entries {
+			state.Entries[key] = &internalpb.Entry{
+				Key:             key,
+				Value:           []byte(nodeID),
+				LastUpdatedTime: timestamppb.New(updated),
+			}
+		}
+		require.NoError(t, fsm.merge(state))
+	}
+	owner := func(t *testing.T, fsm *delegate, key string) string {
+		_, nodeID, err := fsm.get(key)
+		require.NoError(t, err)
+		return nodeID
+	}
+
+	t.Run("With the latest entry winning", func(t *testing.T) {
+		fsm := newTestDelegate()
+		merge(t, fsm, "node1", map[string]time.Time{"key": now})
+		merge(t, fsm, "node2", map[string]time.Time{"key": now.Add(time.Second)})
+		merge(t, fsm, "node3", map[string]time.Time{"key": now.Add(-time.Second)})
+		assert.Equal(t, "node2", owner(t, fsm, "key"))
+
+		entry, metadata, err := fsm.GetWithMetadata(ctx, "key")
+		require.NoError(t, err)
+		assert.Equal(t, []byte("node2"), entry.GetValue())
+		assert.Equal(t, "node2", metadata.GetNodeId())
+		assert.Equal(t, "node", metadata.GetServedBy())
+		assert.Equal(t, versions["node2"], metadata.GetVersion())
+		assert.True(t, now.Add(time.Second).Equal(metadata.GetLastUpdatedTime().AsTime()))
+		assert.Nil(t, metadata.GetTtlRemaining())
+	})
+	t.Run("With ties broken by node id", func(t *testing.T) {
+		fsm := newTestDelegate()
+		merge(t, fsm, "node2", map[string]time.Time{"key": now})
+		merge(t, fsm, "node1", map[string]time.Time{"key": now})
+		assert.Equal(t, "node1", owner(t, fsm, "key"))
+	})
+	t.Run("With the winner removing the key", func(t *testing.T) {
+		fsm := newTestDelegate()
+		merge(t, fsm, "node1", map[string]time.Time{"key": now})
+		merge(t, fsm, "node2", map[string]time.Time{"key": now.Add(time.Second)})
+		merge(t, fsm, "node2", map[string]time.Time{})
+		assert.Equal(t, "node1", owner(t, fsm, "key"))
+
+		merge(t, fsm, "node1", map[string]time.Time{})
+		assert.False(t, fsm.Exists(ctx, "key"))
+		assert.Empty(t, fsm.index)
+	})
+	t.Run("With the winner updating to an older entry", func(t *testing.T) {
+		fsm := newTestDelegate()
+		merge(t, fsm, "node1", map[string]time.Time{"key": now})
+		merge(t, fsm, "node2", map[string]time.Time{"key": now.Add(time.Second)})
+		merge(t, fsm, "node2", map[string]time.Time{"key": now.Add(-time.Second)})
+		assert.Equal(t, "node1", owner(t, fsm, "key"))
+	})
+	t.Run("With the local state winning", func(t *testing.T) {
+		fsm := newTestDelegate()
+		_, err := fsm.Put(ctx, "key", []byte("local"), NoExpiration)
+		require.NoError(t, err)
+		merge(t, fsm, "node1", map[string]time.Time{"key": now.Add(-time.Hour)})
+		assert.Equal(t, "node", owner(t, fsm, "key"))
+		entries := fsm.List(ctx)
+		assert.Len(t, entries, 1)
+	})
+	t.Run("With a peer superseding the local state", func(t *testing.T) {
+		fsm := newTestDelegate()
+		_, err := fsm.Put(ctx, "key", []byte("local"), NoExpiration)
+		require.NoError(t, err)
+		merge(t, fsm, "node1", map[string]time.Time{"key": now.Add(time.Hour)})
+		assert.Equal(t, "node1", owner(t, fsm, "key"))
+
+		// the superseded local entry is dropped
+		_, exists := fsm.local.peek("key")
+		assert.False(t, exists)
+		entries, metadata := fsm.ListWithMetadata(ctx)
+		assert.Len(t, entries, 1)
+		require.Contains(t, metadata, "key")
+		assert.Equal(t, "node1", metadata["key"].GetNodeId())
+		assert.Equal(t, versions["node1"], metadata["key"].GetVersion())
+	})
+	t.Run("With a key deleted on any node", func(t *testing.T) {
+		var changes []*keyChange
+		fsm := newTestDelegate()
+		fsm.onChanges = func(batch []*keyChange) {
+			changes = append(changes, batch...)
+		}
+
+		merge(t, fsm, "node1", map[string]time.Time{"key": now.Add(-time.Second)})
+		assert.True(t, fsm.Delete(ctx, "key"))
+		assert.False(t, fsm.Exists(ctx, "key"))
+		entries := fsm.List(ctx)
+		assert.Empty(t, entries)
+		assert.False(t, fsm.Delete(ctx, "key"))
+
+		// deleting a key not merged yet supersedes the older entry merged afterwards
+		assert.False(t, fsm.Delete(ctx, "unknown"))
+		merge(t, fsm, "node2", map[string]time.Time{"unknown": now.Add(-time.Second)})
+		assert.False(t, fsm.Exists(ctx, "unknown"))
+
+		// the delete is gossiped as a removed entry superseding the peer entry
+		tombstone, exists := fsm.local.peek("key")
+		require.True(t, exists)
+		assert.True(t, tombstone.GetArchived())
+
+		// a later update on a peer supersedes the delete
+		merge(t, fsm, "node1", map[string]time.Time{"key": now.Add(time.Second)})
+		assert.Equal(t, "node1", owner(t, fsm, "key"))
+		require.NotEmpty(t, changes)
+		assert.NotNil(t, changes[len(changes)-1].entry)
+	})
+	t.Run("With adopted entry stamped in the future", func(t *testing.T) {
+		fsm := newTestDelegate()
+		_, err := fsm.Adopt(ctx, &internalpb.Entry{
+			Key:             "key",
+			Value:           []byte("value"),
+			LastUpdatedTime: timestamppb.New(now.Add(time.Hour)),
+		})
+		require.NoError(t, err)
+
+		adopted, exists := fsm.local.peek("key")
+		require.True(t, exists)
+		assert.False(t, adopted.GetLastUpdatedTime().AsTime().After(time.Now()))
+
+		// a later delete supersedes the adopted entry
+		assert.True(t, fsm.Delete(ctx, "key"))
+		assert.False(t, fsm.Exists(ctx, "key"))
+	})
+	t.Run("With stats", func(t *testing.T) {
+		fsm := newTestDelegate()
+		_, err := fsm.Put(ctx, "key", []byte("value"), NoExpiration)
+		require.NoError(t, err)
+		merge(t, fsm, "node1", map[string]time.Time{"key1": now, "key2": now})
+		merge(t, fsm, "node2", map[string]time.Time{"key3": now})
+
+		stats := fsm.stats()
+		assert.Equal(t, 1, stats.localEntries)
+		assert.Equal(t, 8, stats.localBytes)
+		assert.Equal(t, 3, stats.peersEntries)
+		size := proto.Size(fsm.peersState.GetRemoteStates()["node1"]) + proto.Size(fsm.peersState.GetRemoteStates()["node2"])
+		assert.Equal(t, size, stats.peersBytes)
+
+		// the counters follow the merged states
+		merge(t, fsm, "node1", map[string]time.Time{"key1": now})
+		stats = fsm.stats()
+		assert.Equal(t, 2, stats.peersEntries)
+		size = proto.Size(fsm.peersState.GetRemoteStates()["node1"]) + proto.Size(fsm.peersState.GetRemoteStates()["node2"])
+		assert.Equal(t, size, stats.peersBytes)
+	})
+	t.Run("With an invalid payload", func(t *testing.T) {
+		fsm := newTestDelegate()
+		merge(t, fsm, "node1", map[string]time.Time{"key": now})
+
+		fsm.MergeRemoteState([]byte("invalid"), false)
+		bytea, err := proto.Marshal(&internalpb.NodeDigest{Version: 100})
+		require.NoError(t, err)
+		fsm.MergeRemoteState(bytea, false)
+
+		assert.Equal(t, "node1", owner(t, fsm, "key"))
+		assert.Len(t, fsm.peersState.GetRemoteStates(), 1)
+		assert.Empty(t, fsm.transfers)
+	})
+	t.Run("With stale pushes", func(t *testing.T) {
+		var changes []*keyChange
+		fsm := newTestDelegate()
+		fsm.onChanges = func(batch []*keyChange) {
+			changes = append(changes, batch...)
+		}
+
+		push := func(generation, version uint64, keys ...string) error {
+			state := &internalpb.NodeState{
+				NodeId:     "node1",
+				Entries:    make(map[string]*internalpb.Entry, len(keys)),
+				Generation: generation,
+				Version:    version,
+			}
+			for _, key := range keys {
+				state.Entries[key] = &internalpb.Entry{Key: key, LastUpdatedTime: timestamppb.New(now)}
+			}
+			return fsm.merge(state)
+		}
+
+		require.NoError(t, push(2, 5, "key1", "key2"))
+		require.Len(t, changes, 2)
+
+		// an older version of the same generation is rejected
+		assert.ErrorIs(t, push(2, 4, "key1"), errStalePeerState)
+		assert.True(t, fsm.Exists(ctx, "key2"))
+		// the same version does not produce any change
+		require.NoError(t, push(2, 5, "key1", "key2"))
+		assert.Len(t, changes, 2)
+		// a previous generation is rejected
+		assert.ErrorIs(t, push(1, 10), errStalePeerState)
+		assert.True(t, fsm.Exists(ctx, "key1"))
+
+		// a new generation is merged whatever its version
+		require.NoError(t, push(3, 0, "key1"))
+		assert.False(t, fsm.Exists(ctx, "key2"))
+		require.Len(t, changes, 3)
+		assert.Equal(t, "key2", changes[2].key)
+		assert.Nil(t, changes[2].entry)
+	})
+	t.Run("With digests", func(t *testing.T) {
+		states := map[string]*internalpb.NodeState{
+			"node1": {
+				NodeId:     "node1",
+				Generation: 1,
+				Version:    2,
+				Entries: map[string]*internalpb.Entry{
+					"key": {Key: "key", LastUpdatedTime: timestamppb.New(now)},
+				},
+			},
+		}
+
+		fetches := atomic.NewInt32(0)
+		fsm := newTestDelegate()
+		fsm.fetchState = func(_ context.Context, digest *internalpb.NodeDigest) (*internalpb.NodeState, error) {
+			fetches.Inc()
+			return states[digest.GetNodeId()], nil
+		}
+		pushDigest := func(generation, version uint64) {
+			bytea, err := proto.Marshal(&internalpb.NodeDigest{NodeId: "node1", Generation: generation, Version: version})
+			require.NoError(t, err)
+			fsm.MergeRemoteState(bytea, false)
+		}
+
+		pushDigest(1, 2)
+		require.Eventually(t, func() bool { return fsm.Exists(ctx, "key") }, time.Second, 10*time.Millisecond)
+		assert.EqualValues(t, 1, fetches.Load())
+		assert.False(t, fsm.lastSyncTime("node1").IsZero())
+
+		// an up-to-date or an older digest does not transfer the state
+		pushDigest(1, 2)
+		pushDigest(1, 1)
+		assert.EqualValues(t, 1, fetches.Load())
+
+		// a restarted peer without any change is merged without a transfer
+		pushDigest(2, 0)
+		assert.False(t, fsm.Exists(ctx, "key"))
+		assert.EqualValues(t, 1, fetches.Load())
+	})
+}
+
+func TestStreamState(t *testing.T) {
+	ctx := context.Background()
+	fsm := newDelegate("node", new(internalpb.NodeMeta), noopMetrics(), newTracer(nil))
+
+	var chunks []*internalpb.StreamStateResponse
+	collect := func(chunk *internalpb.StreamStateResponse) error {
+		chunks = append(chunks, chunk)
+		return nil
+	}
+
+	// an empty state is sent in a single chunk carrying its version
+	require.NoError(t, fsm.streamState(defaultStateChunkSize, collect))
+	require.Len(t, chunks, 1)
+	assert.Empty(t, chunks[0].GetEntries())
+	assert.Equal(t, fsm.local.generation, chunks[0].GetGeneration())
+
+	for i := 0; i < 10; i++ {
+		_, err := fsm.Put(ctx, strconv.Itoa(i), make([]byte, 100), NoExpiration)
+		require.NoError(t, err)
+	}
+
+	chunks = nil
+	require.NoError(t, fsm.streamState(250, collect))
+	require.Len(t, chunks, 4)
+
+	keys := make(map[string]struct{})
+	for _, chunk := range chunks {
+		assert.Equal(t, "node", chunk.GetNodeId())
+		assert.EqualValues(t, 10, chunk.GetVersion())
+		for _, entry := range chunk.GetEntries() {
+			keys[entry.GetKey()] = struct{}{}
+		}
+	}
+	assert.Len(t, keys, 10)
+}
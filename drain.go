@@ -0,0 +1,179 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2024 Arsene Tochemey Gandote
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package gokv
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"connectrpc.com/connect"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/tochemey/gokv/internal/http"
+	"github.com/tochemey/gokv/internal/internalpb"
+	"github.com/tochemey/gokv/internal/internalpb/internalpbconnect"
+)
+
+// Drain hands off the node local entries to its live peers and then stops the node.
+// Every entry is handed off to the peer placed first for its key by the PlacementStrategy, with its last
+// updated time and expiry, and the node only leaves the cluster once every peer has acknowledged its entries.
+// The writes sent to the node are rejected with ErrNodeDraining during the handoff.
+// When the handoff fails, for instance with ErrNoPeers, the node keeps running and accepts the writes again.
+func (node *Node) Drain(ctx context.Context) error {
+	if err := node.handoff(ctx); err != nil {
+		return err
+	}
+	return node.stop(ctx)
+}
+
+// Handoff adopts the entries handed off by a draining peer.
+// The entries are only accepted from a current member of the cluster calling from its advertised address
+// nolint
+func (node *Node) Handoff(ctx context.Context, request *connect.Request[internalpb.HandoffRequest]) (*connect.Response[internalpb.HandoffResponse], error) {
+	if !node.started.Load() {
+		return nil, connect.NewError(connect.CodeFailedPrecondition, ErrNodeNotStarted)
+	}
+
+	if node.draining.Load() {
+		return nil, connect.NewError(connect.CodeUnavailable, ErrNodeDraining)
+	}
+
+	if !node.isPeer(request.Msg.GetNodeId(), request.Peer().Addr) {
+		return nil, connect.NewError(connect.CodePermissionDenied, ErrNotClusterMember)
+	}
+
+	for _, entry := range request.Msg.GetEntries() {
+		if err := node.checkLimits(entry.GetKey(), entry.GetValue()); err != nil {
+			return nil, err
+		}
+
+		result, err := node.delegate.Adopt(ctx, entry)
+		if err != nil {
+			return nil, limitViolation(connect.CodeResourceExhausted, err)
+		}
+
+		if result != nil {
+			node.notifyPut(result)
+		}
+	}
+	return connect.NewResponse(new(internalpb.HandoffResponse)), nil
+}
+
+// isPeer returns true when the given node is a current member of the cluster, other than the node itself,
+// and the given address is on its advertised host
+func (node *Node) isPeer(nodeID, addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+
+	ip := net.ParseIP(host)
+	for _, member := range node.memberlist.Members() {
+		if member.Name == nodeID && member.Name != node.memberConfig.Name {
+			return ip != nil && ip.Equal(member.Addr)
+		}
+	}
+	return false
+}
+
+// handoff hands off the node local entries to the peers owning them and waits for their acknowledgement.
+// The writes are rejected until the node stops unless the handoff fails
+func (node *Node) handoff(ctx context.Context) error {
+	if !node.started.Load() {
+		return ErrNodeNotStarted
+	}
+
+	if !node.draining.CompareAndSwap(false, true) {
+		return ErrNodeDraining
+	}
+
+	if err := node.handoffEntries(ctx); err != nil {
+		node.draining.Store(false)
+		return err
+	}
+	return nil
+}
+
+// handoffEntries sends the node local entries to the peers placed first for their keys
+func (node *Node) handoffEntries(ctx context.Context) error {
+	entries := node.delegate.local.list()
+	if len(entries) == 0 {
+		return nil
+	}
+
+	peers, err := node.Peers()
+	if err != nil {
+		return err
+	}
+
+	if len(peers) == 0 {
+		return ErrNoPeers
+	}
+
+	owners := make(map[string]*Member, len(peers))
+	handoffs := make(map[string][]*internalpb.Entry, len(peers))
+	for _, entry := range entries {
+		owner := node.config.placement.Place(entry.GetKey(), peers, 1)[0]
+		owners[owner.Name] = owner
+		handoffs[owner.Name] = append(handoffs[owner.Name], entry)
+	}
+
+	for name, entries := range handoffs {
+		if err := node.handoffTo(ctx, owners[name], entries); err != nil {
+			return fmt.Errorf("failed to hand off the entries to %s: %w", name, err)
+		}
+	}
+
+	node.config.logger.Infof("%s handed off %d entries to %d peers", node.discoveryAddress, len(entries), len(handoffs))
+	return nil
+}
+
+// handoffTo sends the given entries to the given peer in chunks of about the state chunk size
+func (node *Node) handoffTo(ctx context.Context, peer *Member, entries []*internalpb.Entry) error {
+	service := internalpbconnect.NewKVServiceClient(node.transferClient,
+		http.URL(peer.Host, int(peer.Port)),
+		connect.WithInterceptors(node.config.interceptors...))
+
+	newRequest := func() *internalpb.HandoffRequest {
+		return &internalpb.HandoffRequest{NodeId: node.memberConfig.Name}
+	}
+
+	request := newRequest()
+	size := 0
+	for i, entry := range entries {
+		request.Entries = append(request.Entries, entry)
+		if size += proto.Size(entry); size < node.config.stateChunkSize && i < len(entries)-1 {
+			continue
+		}
+
+		if _, err := service.Handoff(ctx, connect.NewRequest(request)); err != nil {
+			return err
+		}
+		request = newRequest()
+		size = 0
+	}
+	return nil
+}
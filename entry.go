@@ -24,7 +24,12 @@
 
 package gokv
 
-import "github.com/tochemey/gokv/internal/internalpb"
+import (
+	"bytes"
+	"time"
+
+	"github.com/tochemey/gokv/internal/internalpb"
+)
 
 // Entry represents the key/value pair
 type Entry struct {
@@ -32,11 +37,88 @@ type Entry struct {
 	Key string
 	// Value represents the value
 	Value []byte
+	// TTL is the time to live of the entry set by Put. A zero or negative TTL, such as NoExpiration, means no expiration.
+	// The read entries carry their remaining time to live so that they can be put back as they are.
+	// A read entry put back with a zero TTL after its expiry deadline is rejected with ErrEntryExpired
+	TTL time.Duration
+	// LastUpdated is the last time the entry has been written. It is set on the read entries
+	LastUpdated time.Time
+	// ExpiresAt is the expiry deadline of the entry. It is zero when the entry does not expire.
+	// It is set on the read entries
+	ExpiresAt time.Time
+	// Version is the version of the origin node state the entry has been read from. It is set on the read entries
+	Version uint64
+	// Origin is the id of the node whose state holds the entry. It is set on the read entries
+	Origin string
+}
+
+// EntryMetadata describes where a value read on a node comes from and how fresh it is
+type EntryMetadata struct {
+	// Source is the id of the node whose state holds the entry
+	Source string
+	// ServedBy is the id of the node that served the read
+	ServedBy string
+	// LastUpdated is the last time the entry has been written
+	LastUpdated time.Time
+	// Version is the version of the source node state the entry has been read from
+	Version uint64
+	// TTL is the remaining time to live of the entry. It is zero when the entry does not expire
+	TTL time.Duration
+	// SinceLastSync is the time elapsed since the serving node last merged the source node state.
+	// It is zero when the entry comes from the serving node own state
+	SinceLastSync time.Duration
+}
+
+// KeyChange defines a change of a key in the cluster
+type KeyChange struct {
+	// Key is the changed key
+	Key string
+	// Deleted states whether the key has been removed
+	Deleted bool
+	// Entry is the new entry of the key. It is nil when the key has been removed
+	Entry *Entry
+}
+
+// fromNode converts the given node entry and its metadata, when known, to an Entry
+func fromNode(entry *internalpb.Entry, metadata *internalpb.EntryMetadata) *Entry {
+	result := &Entry{
+		Key:         entry.GetKey(),
+		Value:       entry.GetValue(),
+		LastUpdated: entry.GetLastUpdatedTime().AsTime(),
+		ExpiresAt:   expiresAt(entry),
+		Version:     metadata.GetVersion(),
+		Origin:      metadata.GetNodeId(),
+	}
+
+	if !result.ExpiresAt.IsZero() {
+		result.TTL = max(time.Until(result.ExpiresAt), 0)
+	}
+	return result
+}
+
+// expired returns true when the entry has been read with an expiry deadline that has passed
+// and no TTL has been set since
+func (entry *Entry) expired() bool {
+	return entry.TTL == 0 && !entry.ExpiresAt.IsZero() && !time.Now().Before(entry.ExpiresAt)
+}
+
+// clone returns a copy of the entry with its remaining time to live updated
+func (entry *Entry) clone() *Entry {
+	cloned := *entry
+	cloned.Value = bytes.Clone(entry.Value)
+	if !cloned.ExpiresAt.IsZero() {
+		cloned.TTL = max(time.Until(cloned.ExpiresAt), 0)
+	}
+	return &cloned
 }
 
-func fromNode(entry *internalpb.Entry) *Entry {
-	return &Entry{
-		Key:   entry.Key,
-		Value: entry.Value,
+func fromMetadata(metadata *internalpb.EntryMetadata) *EntryMetadata {
+	return &EntryMetadata{
+		Source:        metadata.GetNodeId(),
+		ServedBy:      metadata.GetServedBy(),
+		LastUpdated:   metadata.GetLastUpdatedTime().AsTime(),
+		Version:       metadata.GetVersion(),
+		TTL:           metadata.GetTtlRemaining().AsDuration(),
+		SinceLastSync: metadata.GetSinceLastSync().AsDuration(),
 	}
 }
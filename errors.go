@@ -32,4 +32,30 @@ var (
 	// ErrKeyNotFound is return when the given key value is not found in the cluster
 	ErrKeyNotFound        = errors.New("key not found")
 	ErrClientNotConnected = errors.New("cluster client not connected")
+	// ErrNodeMetaTooLarge is returned when the node metadata, including its tags, is too large to be gossiped
+	ErrNodeMetaTooLarge = errors.New("node metadata too large")
+	// ErrNoClusterMembers is returned when the cluster client does not know any cluster member
+	ErrNoClusterMembers = errors.New("no cluster members available")
+	// ErrCircuitOpen is returned when the client circuit breaker rejects a call
+	ErrCircuitOpen = errors.New("circuit breaker is open")
+	// ErrKeyTooLarge is returned when the key exceeds the maximum key size of the node
+	ErrKeyTooLarge = errors.New("key too large")
+	// ErrValueTooLarge is returned when the value exceeds the maximum value size of the node
+	ErrValueTooLarge = errors.New("value too large")
+	// ErrTooManyEntries is returned when the node local state holds the maximum number of entries
+	ErrTooManyEntries = errors.New("too many entries")
+	// ErrStoreFull is returned when the node local state reached its maximum size
+	ErrStoreFull = errors.New("node store is full")
+	// ErrWatchLagging is returned when a watch stream is ended because its caller does not keep up with the changes
+	ErrWatchLagging = errors.New("watch stream is lagging")
+	// ErrWatchClosed is returned when a watch stream is ended by the node, for instance when it stops
+	ErrWatchClosed = errors.New("watch stream closed")
+	// ErrNodeDraining is returned when a write is sent to a node handing off its entries before leaving the cluster
+	ErrNodeDraining = errors.New("cluster node is draining")
+	// ErrNoPeers is returned when a node cannot hand off its entries because it does not have any peer
+	ErrNoPeers = errors.New("no peers to hand off the entries to")
+	// ErrNotClusterMember is returned when a node receives entries handed off by a node that is not a member of its cluster
+	ErrNotClusterMember = errors.New("not a cluster member")
+	// ErrEntryExpired is returned when a read entry is put back after its expiry deadline without a new TTL
+	ErrEntryExpired = errors.New("entry has expired")
 )
@@ -29,12 +29,28 @@ import (
 	"time"
 )
 
+// EventType defines the cluster event type
 type EventType int
 
 const (
+	// NodeJoined is emitted when a node joins the cluster
 	NodeJoined EventType = iota
+	// NodeLeft is emitted when a node gracefully leaves the cluster
 	NodeLeft
+	// NodeDead is emitted when a node is declared dead by the failure detector.
+	// There is no suspect event: memberlist neither notifies nor exposes the suspect state of its members
 	NodeDead
+	// NodeUpdated is emitted when a node metadata changes
+	NodeUpdated
+	// KeyAdded is emitted when a key is added to the node local state
+	KeyAdded
+	// KeyUpdated is emitted when an existing key of the node local state is updated
+	KeyUpdated
+	// KeyDeleted is emitted when a key is deleted from the node local state
+	KeyDeleted
+	// KeyEvicted is emitted when a key is evicted from the node local state
+	// to make room for a write exceeding the node storage limits
+	KeyEvicted
 )
 
 func (et EventType) String() string {
@@ -45,6 +61,16 @@ func (et EventType) String() string {
 		return "NodeLeft"
 	case NodeDead:
 		return "NodeDead"
+	case NodeUpdated:
+		return "NodeUpdated"
+	case KeyAdded:
+		return "KeyAdded"
+	case KeyUpdated:
+		return "KeyUpdated"
+	case KeyDeleted:
+		return "KeyDeleted"
+	case KeyEvicted:
+		return "KeyEvicted"
 	default:
 		return fmt.Sprintf("%d", int(et))
 	}
@@ -52,7 +78,11 @@ func (et EventType) String() string {
 
 // Event defines the cluster event
 type Event struct {
+	// Member is the member the event is about.
+	// For key events this is the node where the key has changed
 	Member *Member
 	Time   time.Time
 	Type   EventType
+	// Key is the key the event is about. It is only set for key events
+	Key string
 }
@@ -0,0 +1,158 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2024 Arsene Tochemey Gandote
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package gokv
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"go.uber.org/atomic"
+
+	"github.com/tochemey/gokv/internal/internalpb"
+)
+
+// EvictionPolicy defines how the node makes room in its local state
+// when a write exceeds the maximum number of entries or the maximum total size
+type EvictionPolicy int
+
+const (
+	// NoEviction rejects the writes exceeding the node storage limits
+	NoEviction EvictionPolicy = iota
+	// LRU evicts the least recently used entries first
+	LRU
+	// LFU evicts the least frequently used entries first
+	LFU
+	// TTLFirst evicts the entries expiring the soonest first
+	// and then the least recently used entries without expiry
+	TTLFirst
+)
+
+func (policy EvictionPolicy) String() string {
+	switch policy {
+	case NoEviction:
+		return "NoEviction"
+	case LRU:
+		return "LRU"
+	case LFU:
+		return "LFU"
+	case TTLFirst:
+		return "TTLFirst"
+	default:
+		return fmt.Sprintf("%d", int(policy))
+	}
+}
+
+// evictionSamples is the number of entries sampled to pick the next entry to evict.
+// As Redis does, the policies are approximated to not walk the whole local state on every write
+const evictionSamples = 16
+
+// keyAccess holds the access statistics of a key of the node local state
+type keyAccess struct {
+	hits       *atomic.Uint64
+	lastAccess *atomic.Int64
+}
+
+// newKeyAccess creates an instance of keyAccess
+func newKeyAccess() *keyAccess {
+	return &keyAccess{
+		hits:       atomic.NewUint64(0),
+		lastAccess: atomic.NewInt64(0),
+	}
+}
+
+// touch records an access to the key
+func (access *keyAccess) touch() {
+	access.hits.Inc()
+	access.lastAccess.Store(time.Now().UnixNano())
+}
+
+// evictionCandidate defines an entry of the node local state that can be evicted
+type evictionCandidate struct {
+	key        string
+	size       int64
+	expired    bool
+	expiresAt  time.Time
+	hits       uint64
+	lastAccess int64
+}
+
+// newEvictionCandidate creates an instance of evictionCandidate for the given entry of the given shard.
+// The caller must hold the shard lock
+func newEvictionCandidate(shard *storeShard, key string, entry *internalpb.Entry) *evictionCandidate {
+	candidate := &evictionCandidate{
+		key:       key,
+		size:      entrySize(key, entry.GetValue()),
+		expired:   expired(entry),
+		expiresAt: expiresAt(entry),
+	}
+
+	if access, ok := shard.access[key]; ok {
+		candidate.hits = access.hits.Load()
+		candidate.lastAccess = access.lastAccess.Load()
+	}
+	return candidate
+}
+
+// sortCandidates orders the eviction candidates according to the given policy.
+// The expired entries always come first
+func sortCandidates(candidates []*evictionCandidate, policy EvictionPolicy) {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		left, right := candidates[i], candidates[j]
+		if left.expired != right.expired {
+			return left.expired
+		}
+
+		switch policy {
+		case LFU:
+			if left.hits != right.hits {
+				return left.hits < right.hits
+			}
+		case TTLFirst:
+			if left.expiresAt.IsZero() != right.expiresAt.IsZero() {
+				return !left.expiresAt.IsZero()
+			}
+			if !left.expiresAt.Equal(right.expiresAt) {
+				return left.expiresAt.Before(right.expiresAt)
+			}
+		}
+		return left.lastAccess < right.lastAccess
+	})
+}
+
+// expiresAt returns the expiration time of the given entry or the zero time
+// when the entry does not expire
+func expiresAt(entry *internalpb.Entry) time.Time {
+	if entry.GetExpiry() == nil {
+		return time.Time{}
+	}
+	return entry.GetLastUpdatedTime().AsTime().Add(entry.GetExpiry().AsDuration())
+}
+
+// entrySize returns the size in bytes of the given key/value pair
+// accounted against the node maximum total size
+func entrySize(key string, value []byte) int64 {
+	return int64(len(key) + len(value))
+}
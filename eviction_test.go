@@ -0,0 +1,185 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2024 Arsene Tochemey Gandote
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package gokv
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tochemey/gokv/internal/internalpb"
+)
+
+func TestEviction(t *testing.T) {
+	ctx := context.Background()
+	newTestDelegate := func() *delegate {
+		return newDelegate("node", new(internalpb.NodeMeta), noopMetrics(), newTracer(nil))
+	}
+
+	t.Run("With LRU", func(t *testing.T) {
+		fsm := newTestDelegate()
+		fsm.Put(ctx, "key1", []byte("value"), NoExpiration)
+		fsm.Put(ctx, "key2", []byte("value"), NoExpiration)
+		fsm.Put(ctx, "key3", []byte("value"), NoExpiration)
+		// key1 becomes the most recently used
+		_, err := fsm.Get(ctx, "key1")
+		require.NoError(t, err)
+
+		fsm.limits = storageLimits{maxEntries: 3, policy: LRU}
+		result, err := fsm.Put(ctx, "key4", []byte("value"), NoExpiration)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"key2"}, result.evicted)
+		assert.False(t, fsm.Exists(ctx, "key2"))
+		assert.True(t, fsm.Exists(ctx, "key1"))
+
+		// the eviction supersedes the older entries of the key held by the peers
+		tombstone, exists := fsm.local.peek("key2")
+		require.True(t, exists)
+		assert.True(t, tombstone.GetArchived())
+		assert.EqualValues(t, 3, fsm.local.len())
+	})
+	t.Run("With LFU", func(t *testing.T) {
+		fsm := newTestDelegate()
+		fsm.Put(ctx, "key1", []byte("value"), NoExpiration)
+		fsm.Put(ctx, "key2", []byte("value"), NoExpiration)
+		fsm.Put(ctx, "key3", []byte("value"), NoExpiration)
+		for _, key := range []string{"key1", "key1", "key3"} {
+			_, err := fsm.Get(ctx, key)
+			require.NoError(t, err)
+		}
+
+		fsm.limits = storageLimits{maxEntries: 2, policy: LFU}
+		result, err := fsm.Put(ctx, "key4", []byte("value"), NoExpiration)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"key2", "key3"}, result.evicted)
+		assert.True(t, fsm.Exists(ctx, "key1"))
+	})
+	t.Run("With TTLFirst", func(t *testing.T) {
+		fsm := newTestDelegate()
+		fsm.Put(ctx, "key1", []byte("value"), NoExpiration)
+		fsm.Put(ctx, "key2", []byte("value"), time.Hour)
+		fsm.Put(ctx, "key3", []byte("value"), time.Minute)
+
+		fsm.limits = storageLimits{maxEntries: 2, policy: TTLFirst}
+		result, err := fsm.Put(ctx, "key4", []byte("value"), NoExpiration)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"key3", "key2"}, result.evicted)
+		assert.True(t, fsm.Exists(ctx, "key1"))
+	})
+	t.Run("With expired entries first", func(t *testing.T) {
+		fsm := newTestDelegate()
+		fsm.Put(ctx, "key1", []byte("value"), NoExpiration)
+		fsm.Put(ctx, "key2", []byte("value"), time.Millisecond)
+		time.Sleep(10 * time.Millisecond)
+
+		fsm.limits = storageLimits{maxEntries: 2, policy: LRU}
+		result, err := fsm.Put(ctx, "key3", []byte("value"), NoExpiration)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"key2"}, result.evicted)
+	})
+	t.Run("With max total size", func(t *testing.T) {
+		fsm := newTestDelegate()
+		fsm.Put(ctx, "key1", []byte("value"), NoExpiration)
+		fsm.Put(ctx, "key2", []byte("value"), NoExpiration)
+
+		// each entry accounts for 9 bytes
+		fsm.limits = storageLimits{maxTotalSize: 20, policy: LRU}
+		result, err := fsm.Put(ctx, "key3", []byte("value"), NoExpiration)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"key1"}, result.evicted)
+
+		// the replaced key does not count against the limits
+		fsm.limits = storageLimits{maxTotalSize: 18, policy: LRU}
+		result, err = fsm.Put(ctx, "key2", []byte("value"), NoExpiration)
+		require.NoError(t, err)
+		assert.Empty(t, result.evicted)
+		assert.True(t, result.updated)
+	})
+	t.Run("With a value exceeding the max total size", func(t *testing.T) {
+		fsm := newTestDelegate()
+		fsm.Put(ctx, "key1", []byte("value"), NoExpiration)
+
+		fsm.limits = storageLimits{maxTotalSize: 10, policy: LRU}
+		_, err := fsm.Put(ctx, "key2", []byte("very-long-value"), NoExpiration)
+		assert.ErrorIs(t, err, ErrStoreFull)
+		assert.True(t, fsm.Exists(ctx, "key1"))
+		assert.False(t, fsm.Exists(ctx, "key2"))
+	})
+	t.Run("With access statistics removed on delete", func(t *testing.T) {
+		fsm := newTestDelegate()
+		fsm.Put(ctx, "key", []byte("value"), NoExpiration)
+		shard := fsm.local.shard("key")
+		require.Contains(t, shard.access, "key")
+		fsm.Delete(ctx, "key")
+		assert.NotContains(t, shard.access, "key")
+	})
+	t.Run("With no eviction", func(t *testing.T) {
+		fsm := newTestDelegate()
+		fsm.Put(ctx, "key1", []byte("value"), NoExpiration)
+		fsm.limits = storageLimits{maxEntries: 1}
+		_, err := fsm.Put(ctx, "key2", []byte("value"), NoExpiration)
+		assert.ErrorIs(t, err, ErrTooManyEntries)
+		fsm.limits = storageLimits{maxTotalSize: 10}
+		_, err = fsm.Put(ctx, "key1", []byte("very-long-value"), NoExpiration)
+		assert.ErrorIs(t, err, ErrStoreFull)
+		assert.EqualValues(t, 1, fsm.local.len())
+		assert.EqualValues(t, 9, fsm.local.bytes())
+	})
+	t.Run("With removed entries not counted", func(t *testing.T) {
+		fsm := newTestDelegate()
+		fsm.limits = storageLimits{maxEntries: 2}
+		fsm.Put(ctx, "key1", []byte("value"), NoExpiration)
+		fsm.Put(ctx, "key2", []byte("value"), NoExpiration)
+		assert.True(t, fsm.Delete(ctx, "key1"))
+		assert.True(t, fsm.Delete(ctx, "key2"))
+		assert.Zero(t, fsm.local.len())
+		assert.Zero(t, fsm.local.bytes())
+
+		_, err := fsm.Put(ctx, "key3", []byte("value"), NoExpiration)
+		require.NoError(t, err)
+		_, err = fsm.Put(ctx, "key1", []byte("value"), NoExpiration)
+		require.NoError(t, err)
+		assert.EqualValues(t, 2, fsm.local.len())
+	})
+	t.Run("With removed entries never evicted", func(t *testing.T) {
+		fsm := newTestDelegate()
+		fsm.Put(ctx, "key1", []byte("value"), NoExpiration)
+		fsm.Put(ctx, "key2", []byte("value"), NoExpiration)
+		fsm.Put(ctx, "key3", []byte("value"), NoExpiration)
+		assert.True(t, fsm.Delete(ctx, "key1"))
+
+		fsm.limits = storageLimits{maxEntries: 2, policy: LRU}
+		result, err := fsm.Put(ctx, "key4", []byte("value"), NoExpiration)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"key2"}, result.evicted)
+
+		tombstone, exists := fsm.local.peek("key1")
+		require.True(t, exists)
+		assert.True(t, tombstone.GetArchived())
+	})
+}
@@ -100,7 +100,7 @@ func main() {
 
 	// let us distribute the key in the cluster. At the moment we only have one node
 	// Put will override an existing key in the cluster
-	if err := client.Put(ctx, &gokv.Entry{Key: key, Value: value}, gokv.NoExpiration); err != nil {
+	if err := client.Put(ctx, &gokv.Entry{Key: key, Value: value}); err != nil {
 		logger.Fatal(err)
 	}
 
@@ -0,0 +1,75 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2024 Arsene Tochemey Gandote
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package gokv
+
+import (
+	nethttp "net/http"
+
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// healthz reports whether the node is alive
+func (node *Node) healthz(writer nethttp.ResponseWriter, _ *nethttp.Request) {
+	if !node.started.Load() {
+		nethttp.Error(writer, ErrNodeNotStarted.Error(), nethttp.StatusServiceUnavailable)
+		return
+	}
+	writer.WriteHeader(nethttp.StatusOK)
+	_, _ = writer.Write([]byte("ok"))
+}
+
+// readyz reports whether the node has joined the cluster and completed its first push/pull
+func (node *Node) readyz(writer nethttp.ResponseWriter, _ *nethttp.Request) {
+	if !node.ready() {
+		nethttp.Error(writer, "not ready", nethttp.StatusServiceUnavailable)
+		return
+	}
+	writer.WriteHeader(nethttp.StatusOK)
+	_, _ = writer.Write([]byte("ok"))
+}
+
+// cluster returns the cluster members as seen by the node in JSON
+func (node *Node) cluster(writer nethttp.ResponseWriter, _ *nethttp.Request) {
+	if !node.started.Load() {
+		nethttp.Error(writer, ErrNodeNotStarted.Error(), nethttp.StatusServiceUnavailable)
+		return
+	}
+
+	info, err := node.clusterInfo()
+	if err != nil {
+		nethttp.Error(writer, err.Error(), nethttp.StatusInternalServerError)
+		return
+	}
+
+	bytea, err := protojson.Marshal(info)
+	if err != nil {
+		nethttp.Error(writer, err.Error(), nethttp.StatusInternalServerError)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(nethttp.StatusOK)
+	_, _ = writer.Write(bytea)
+}
@@ -37,8 +37,34 @@ import (
 	"golang.org/x/net/http2/h2c"
 )
 
+// ClientConfig defines the http client transport settings
+type ClientConfig struct {
+	// PingTimeout is the timeout after which the connection will be closed
+	// if a response to a health check ping is not received
+	PingTimeout time.Duration
+	// ReadIdleTimeout is the timeout after which a health check ping is sent
+	// when no frame has been received on the connection
+	ReadIdleTimeout time.Duration
+	// IdleConnTimeout is the maximum amount of time an idle connection
+	// will remain idle before closing itself. Zero means no limit
+	IdleConnTimeout time.Duration
+}
+
+// DefaultClientConfig returns the default http client transport settings
+func DefaultClientConfig() *ClientConfig {
+	return &ClientConfig{
+		PingTimeout:     30 * time.Second,
+		ReadIdleTimeout: 30 * time.Second,
+	}
+}
+
 // NewClient creates a http client use h2c
-func NewClient() *http.Client {
+// The default settings are used when the config is nil
+func NewClient(config *ClientConfig) *http.Client {
+	if config == nil {
+		config = DefaultClientConfig()
+	}
+
 	return &http.Client{
 		// Most RPC servers don't use HTTP redirects
 		CheckRedirect: func(_ *http.Request, _ []*http.Request) error {
@@ -52,35 +78,69 @@ func NewClient() *http.Client {
 				// allow-list.
 				return net.Dial(network, addr)
 			},
-			PingTimeout:     30 * time.Second,
-			ReadIdleTimeout: 30 * time.Second,
+			PingTimeout:     config.PingTimeout,
+			ReadIdleTimeout: config.ReadIdleTimeout,
+			IdleConnTimeout: config.IdleConnTimeout,
 		},
 	}
 }
 
+// ServerConfig defines the http server settings
+// reference: https://adam-p.ca/blog/2022/01/golang-http-server-timeouts/
+type ServerConfig struct {
+	// ReadTimeout is the maximum duration for reading the entire request, including the body.
+	// It’s implemented in net/http by calling SetReadDeadline immediately after Accept
+	// ReadTimeout := handler_timeout + ReadHeaderTimeout + wiggle_room
+	ReadTimeout time.Duration
+	// ReadHeaderTimeout is the amount of time allowed to read request headers
+	ReadHeaderTimeout time.Duration
+	// WriteTimeout is the maximum duration before timing out writes of the response.
+	// It is reset whenever a new request’s header is read.
+	// This effectively covers the lifetime of the ServeHTTP handler stack
+	WriteTimeout time.Duration
+	// IdleTimeout is the maximum amount of time to wait for the next request when keep-alive are enabled.
+	// If IdleTimeout is zero, the value of ReadTimeout is used. Not relevant to request timeouts
+	IdleTimeout time.Duration
+	// MaxRequestBodySize is the maximum size in bytes of a request body. Zero means no limit
+	MaxRequestBodySize int64
+	// MaxConcurrentStreams is the maximum number of concurrent HTTP/2 streams per connection.
+	// Zero means the HTTP/2 default
+	MaxConcurrentStreams uint32
+}
+
+// DefaultServerConfig returns the default http server settings
+func DefaultServerConfig() *ServerConfig {
+	return &ServerConfig{
+		ReadTimeout:       3 * time.Second,
+		ReadHeaderTimeout: time.Second,
+		WriteTimeout:      time.Second,
+		IdleTimeout:       1200 * time.Second,
+	}
+}
+
 // NewServer returns an instance of an http server
-func NewServer(ctx context.Context, host string, port int, mux *http.ServeMux) *http.Server {
-	// TODO revisit the timeouts
-	// reference: https://adam-p.ca/blog/2022/01/golang-http-server-timeouts/
+// The default settings are used when the config is nil
+func NewServer(ctx context.Context, host string, port int, mux *http.ServeMux, config *ServerConfig) *http.Server {
+	if config == nil {
+		config = DefaultServerConfig()
+	}
+
+	var handler http.Handler = mux
+	if config.MaxRequestBodySize > 0 {
+		handler = http.MaxBytesHandler(mux, config.MaxRequestBodySize)
+	}
+
 	return &http.Server{
-		Addr: net.JoinHostPort(host, strconv.Itoa(port)),
-		// The maximum duration for reading the entire request, including the body.
-		// It’s implemented in net/http by calling SetReadDeadline immediately after Accept
-		// ReadTimeout := handler_timeout + ReadHeaderTimeout + wiggle_room
-		ReadTimeout: 3 * time.Second,
-		// ReadHeaderTimeout is the amount of time allowed to read request headers
-		ReadHeaderTimeout: time.Second,
-		// WriteTimeout is the maximum duration before timing out writes of the response.
-		// It is reset whenever a new request’s header is read.
-		// This effectively covers the lifetime of the ServeHTTP handler stack
-		WriteTimeout: time.Second,
-		// IdleTimeout is the maximum amount of time to wait for the next request when keep-alive are enabled.
-		// If IdleTimeout is zero, the value of ReadTimeout is used. Not relevant to request timeouts
-		IdleTimeout: 1200 * time.Second,
+		Addr:              net.JoinHostPort(host, strconv.Itoa(port)),
+		ReadTimeout:       config.ReadTimeout,
+		ReadHeaderTimeout: config.ReadHeaderTimeout,
+		WriteTimeout:      config.WriteTimeout,
+		IdleTimeout:       config.IdleTimeout,
 		// For gRPC clients, it's convenient to support HTTP/2 without TLS. You can
 		// avoid x/net/http2 by using http.ListenAndServeTLS.
-		Handler: h2c.NewHandler(mux, &http2.Server{
-			IdleTimeout: 1200 * time.Second,
+		Handler: h2c.NewHandler(handler, &http2.Server{
+			IdleTimeout:          config.IdleTimeout,
+			MaxConcurrentStreams: config.MaxConcurrentStreams,
 		}),
 		BaseContext: func(_ net.Listener) context.Context {
 			return ctx
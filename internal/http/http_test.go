@@ -26,17 +26,20 @@ package http
 
 import (
 	"context"
+	"io"
 	"net/http"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/travisjeffery/go-dynaport"
 	"golang.org/x/net/http2"
 )
 
 func TestNewClient(t *testing.T) {
-	cl := NewClient()
+	cl := NewClient(nil)
 	assert.IsType(t, new(http.Client), cl)
 	assert.IsType(t, new(http2.Transport), cl.Transport)
 	tr := cl.Transport.(*http2.Transport)
@@ -45,15 +48,72 @@ func TestNewClient(t *testing.T) {
 	assert.Equal(t, 30*time.Second, tr.ReadIdleTimeout)
 }
 
+func TestNewClientWithConfig(t *testing.T) {
+	cl := NewClient(&ClientConfig{
+		PingTimeout:     time.Second,
+		ReadIdleTimeout: 2 * time.Second,
+		IdleConnTimeout: 3 * time.Second,
+	})
+	tr := cl.Transport.(*http2.Transport)
+	assert.Equal(t, time.Second, tr.PingTimeout)
+	assert.Equal(t, 2*time.Second, tr.ReadIdleTimeout)
+	assert.Equal(t, 3*time.Second, tr.IdleConnTimeout)
+}
+
 func TestNewServer(t *testing.T) {
 	host := "127.0.0.1"
 	port := dynaport.Get(1)[0]
 	mux := http.NewServeMux()
 	ctx := context.TODO()
 
-	server := NewServer(ctx, host, port, mux)
+	server := NewServer(ctx, host, port, mux, nil)
 	assert.NotNil(t, server)
 	assert.IsType(t, new(http.Server), server)
+	assert.Equal(t, 3*time.Second, server.ReadTimeout)
+	assert.Equal(t, time.Second, server.WriteTimeout)
+}
+
+func TestNewServerWithConfig(t *testing.T) {
+	host := "127.0.0.1"
+	port := dynaport.Get(1)[0]
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(writer http.ResponseWriter, request *http.Request) {
+		if _, err := io.ReadAll(request.Body); err != nil {
+			writer.WriteHeader(http.StatusRequestEntityTooLarge)
+			return
+		}
+		writer.WriteHeader(http.StatusOK)
+	})
+
+	server := NewServer(context.TODO(), host, port, mux, &ServerConfig{
+		ReadTimeout:        5 * time.Second,
+		ReadHeaderTimeout:  2 * time.Second,
+		WriteTimeout:       10 * time.Second,
+		IdleTimeout:        time.Minute,
+		MaxRequestBodySize: 8,
+	})
+	assert.Equal(t, 5*time.Second, server.ReadTimeout)
+	assert.Equal(t, 2*time.Second, server.ReadHeaderTimeout)
+	assert.Equal(t, 10*time.Second, server.WriteTimeout)
+	assert.Equal(t, time.Minute, server.IdleTimeout)
+
+	go func() { _ = server.ListenAndServe() }()
+	t.Cleanup(func() { _ = server.Close() })
+
+	client := NewClient(nil)
+	require.Eventually(t, func() bool {
+		response, err := client.Post(URL(host, port), "text/plain", strings.NewReader("small"))
+		if err != nil {
+			return false
+		}
+		_ = response.Body.Close()
+		return response.StatusCode == http.StatusOK
+	}, time.Second, 10*time.Millisecond)
+
+	response, err := client.Post(URL(host, port), "text/plain", strings.NewReader("a too large body"))
+	require.NoError(t, err)
+	require.NoError(t, response.Body.Close())
+	assert.Equal(t, http.StatusRequestEntityTooLarge, response.StatusCode)
 }
 
 func TestURL(t *testing.T) {
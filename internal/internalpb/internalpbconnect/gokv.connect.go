@@ -43,16 +43,28 @@ const (
 	KVServiceKeyExistsProcedure = "/internalpb.KVService/KeyExists"
 	// KVServiceListProcedure is the fully-qualified name of the KVService's List RPC.
 	KVServiceListProcedure = "/internalpb.KVService/List"
+	// KVServiceClusterInfoProcedure is the fully-qualified name of the KVService's ClusterInfo RPC.
+	KVServiceClusterInfoProcedure = "/internalpb.KVService/ClusterInfo"
+	// KVServiceWatchProcedure is the fully-qualified name of the KVService's Watch RPC.
+	KVServiceWatchProcedure = "/internalpb.KVService/Watch"
+	// KVServiceStreamStateProcedure is the fully-qualified name of the KVService's StreamState RPC.
+	KVServiceStreamStateProcedure = "/internalpb.KVService/StreamState"
+	// KVServiceHandoffProcedure is the fully-qualified name of the KVService's Handoff RPC.
+	KVServiceHandoffProcedure = "/internalpb.KVService/Handoff"
 )
 
 // These variables are the protoreflect.Descriptor objects for the RPCs defined in this package.
 var (
-	kVServiceServiceDescriptor         = internalpb.File_internal_gokv_proto.Services().ByName("KVService")
-	kVServicePutMethodDescriptor       = kVServiceServiceDescriptor.Methods().ByName("Put")
-	kVServiceGetMethodDescriptor       = kVServiceServiceDescriptor.Methods().ByName("Get")
-	kVServiceDeleteMethodDescriptor    = kVServiceServiceDescriptor.Methods().ByName("Delete")
-	kVServiceKeyExistsMethodDescriptor = kVServiceServiceDescriptor.Methods().ByName("KeyExists")
-	kVServiceListMethodDescriptor      = kVServiceServiceDescriptor.Methods().ByName("List")
+	kVServiceServiceDescriptor           = internalpb.File_internal_gokv_proto.Services().ByName("KVService")
+	kVServicePutMethodDescriptor         = kVServiceServiceDescriptor.Methods().ByName("Put")
+	kVServiceGetMethodDescriptor         = kVServiceServiceDescriptor.Methods().ByName("Get")
+	kVServiceDeleteMethodDescriptor      = kVServiceServiceDescriptor.Methods().ByName("Delete")
+	kVServiceKeyExistsMethodDescriptor   = kVServiceServiceDescriptor.Methods().ByName("KeyExists")
+	kVServiceListMethodDescriptor        = kVServiceServiceDescriptor.Methods().ByName("List")
+	kVServiceClusterInfoMethodDescriptor = kVServiceServiceDescriptor.Methods().ByName("ClusterInfo")
+	kVServiceWatchMethodDescriptor       = kVServiceServiceDescriptor.Methods().ByName("Watch")
+	kVServiceStreamStateMethodDescriptor = kVServiceServiceDescriptor.Methods().ByName("StreamState")
+	kVServiceHandoffMethodDescriptor     = kVServiceServiceDescriptor.Methods().ByName("Handoff")
 )
 
 // KVServiceClient is a client for the internalpb.KVService service.
@@ -67,6 +79,14 @@ type KVServiceClient interface {
 	KeyExists(context.Context, *connect.Request[internalpb.KeyExistsRequest]) (*connect.Response[internalpb.KeyExistResponse], error)
 	// List returns the list of all entries at a given point in time
 	List(context.Context, *connect.Request[internalpb.ListRequest]) (*connect.Response[internalpb.ListResponse], error)
+	// ClusterInfo returns the cluster members as seen by the node
+	ClusterInfo(context.Context, *connect.Request[internalpb.ClusterInfoRequest]) (*connect.Response[internalpb.ClusterInfoResponse], error)
+	// Watch streams the changes of the cluster keys as seen by the node
+	Watch(context.Context, *connect.Request[internalpb.WatchRequest]) (*connect.ServerStreamForClient[internalpb.WatchResponse], error)
+	// StreamState streams the node local state in chunks to a peer
+	StreamState(context.Context, *connect.Request[internalpb.StreamStateRequest]) (*connect.ServerStreamForClient[internalpb.StreamStateResponse], error)
+	// Handoff transfers the ownership of the entries of a draining node to a peer
+	Handoff(context.Context, *connect.Request[internalpb.HandoffRequest]) (*connect.Response[internalpb.HandoffResponse], error)
 }
 
 // NewKVServiceClient constructs a client for the internalpb.KVService service. By default, it uses
@@ -109,16 +129,44 @@ func NewKVServiceClient(httpClient connect.HTTPClient, baseURL string, opts ...c
 			connect.WithSchema(kVServiceListMethodDescriptor),
 			connect.WithClientOptions(opts...),
 		),
+		clusterInfo: connect.NewClient[internalpb.ClusterInfoRequest, internalpb.ClusterInfoResponse](
+			httpClient,
+			baseURL+KVServiceClusterInfoProcedure,
+			connect.WithSchema(kVServiceClusterInfoMethodDescriptor),
+			connect.WithClientOptions(opts...),
+		),
+		watch: connect.NewClient[internalpb.WatchRequest, internalpb.WatchResponse](
+			httpClient,
+			baseURL+KVServiceWatchProcedure,
+			connect.WithSchema(kVServiceWatchMethodDescriptor),
+			connect.WithClientOptions(opts...),
+		),
+		streamState: connect.NewClient[internalpb.StreamStateRequest, internalpb.StreamStateResponse](
+			httpClient,
+			baseURL+KVServiceStreamStateProcedure,
+			connect.WithSchema(kVServiceStreamStateMethodDescriptor),
+			connect.WithClientOptions(opts...),
+		),
+		handoff: connect.NewClient[internalpb.HandoffRequest, internalpb.HandoffResponse](
+			httpClient,
+			baseURL+KVServiceHandoffProcedure,
+			connect.WithSchema(kVServiceHandoffMethodDescriptor),
+			connect.WithClientOptions(opts...),
+		),
 	}
 }
 
 // kVServiceClient implements KVServiceClient.
 type kVServiceClient struct {
-	put       *connect.Client[internalpb.PutRequest, internalpb.PutResponse]
-	get       *connect.Client[internalpb.GetRequest, internalpb.GetResponse]
-	delete    *connect.Client[internalpb.DeleteRequest, internalpb.DeleteResponse]
-	keyExists *connect.Client[internalpb.KeyExistsRequest, internalpb.KeyExistResponse]
-	list      *connect.Client[internalpb.ListRequest, internalpb.ListResponse]
+	put         *connect.Client[internalpb.PutRequest, internalpb.PutResponse]
+	get         *connect.Client[internalpb.GetRequest, internalpb.GetResponse]
+	delete      *connect.Client[internalpb.DeleteRequest, internalpb.DeleteResponse]
+	keyExists   *connect.Client[internalpb.KeyExistsRequest, internalpb.KeyExistResponse]
+	list        *connect.Client[internalpb.ListRequest, internalpb.ListResponse]
+	clusterInfo *connect.Client[internalpb.ClusterInfoRequest, internalpb.ClusterInfoResponse]
+	watch       *connect.Client[internalpb.WatchRequest, internalpb.WatchResponse]
+	streamState *connect.Client[internalpb.StreamStateRequest, internalpb.StreamStateResponse]
+	handoff     *connect.Client[internalpb.HandoffRequest, internalpb.HandoffResponse]
 }
 
 // Put calls internalpb.KVService.Put.
@@ -146,6 +194,26 @@ func (c *kVServiceClient) List(ctx context.Context, req *connect.Request[interna
 	return c.list.CallUnary(ctx, req)
 }
 
+// ClusterInfo calls internalpb.KVService.ClusterInfo.
+func (c *kVServiceClient) ClusterInfo(ctx context.Context, req *connect.Request[internalpb.ClusterInfoRequest]) (*connect.Response[internalpb.ClusterInfoResponse], error) {
+	return c.clusterInfo.CallUnary(ctx, req)
+}
+
+// Watch calls internalpb.KVService.Watch.
+func (c *kVServiceClient) Watch(ctx context.Context, req *connect.Request[internalpb.WatchRequest]) (*connect.ServerStreamForClient[internalpb.WatchResponse], error) {
+	return c.watch.CallServerStream(ctx, req)
+}
+
+// StreamState calls internalpb.KVService.StreamState.
+func (c *kVServiceClient) StreamState(ctx context.Context, req *connect.Request[internalpb.StreamStateRequest]) (*connect.ServerStreamForClient[internalpb.StreamStateResponse], error) {
+	return c.streamState.CallServerStream(ctx, req)
+}
+
+// Handoff calls internalpb.KVService.Handoff.
+func (c *kVServiceClient) Handoff(ctx context.Context, req *connect.Request[internalpb.HandoffRequest]) (*connect.Response[internalpb.HandoffResponse], error) {
+	return c.handoff.CallUnary(ctx, req)
+}
+
 // KVServiceHandler is an implementation of the internalpb.KVService service.
 type KVServiceHandler interface {
 	// Put is used to distribute a key/value pair across a cluster of nodes
@@ -158,6 +226,14 @@ type KVServiceHandler interface {
 	KeyExists(context.Context, *connect.Request[internalpb.KeyExistsRequest]) (*connect.Response[internalpb.KeyExistResponse], error)
 	// List returns the list of all entries at a given point in time
 	List(context.Context, *connect.Request[internalpb.ListRequest]) (*connect.Response[internalpb.ListResponse], error)
+	// ClusterInfo returns the cluster members as seen by the node
+	ClusterInfo(context.Context, *connect.Request[internalpb.ClusterInfoRequest]) (*connect.Response[internalpb.ClusterInfoResponse], error)
+	// Watch streams the changes of the cluster keys as seen by the node
+	Watch(context.Context, *connect.Request[internalpb.WatchRequest], *connect.ServerStream[internalpb.WatchResponse]) error
+	// StreamState streams the node local state in chunks to a peer
+	StreamState(context.Context, *connect.Request[internalpb.StreamStateRequest], *connect.ServerStream[internalpb.StreamStateResponse]) error
+	// Handoff transfers the ownership of the entries of a draining node to a peer
+	Handoff(context.Context, *connect.Request[internalpb.HandoffRequest]) (*connect.Response[internalpb.HandoffResponse], error)
 }
 
 // NewKVServiceHandler builds an HTTP handler from the service implementation. It returns the path
@@ -196,6 +272,30 @@ func NewKVServiceHandler(svc KVServiceHandler, opts ...connect.HandlerOption) (s
 		connect.WithSchema(kVServiceListMethodDescriptor),
 		connect.WithHandlerOptions(opts...),
 	)
+	kVServiceClusterInfoHandler := connect.NewUnaryHandler(
+		KVServiceClusterInfoProcedure,
+		svc.ClusterInfo,
+		connect.WithSchema(kVServiceClusterInfoMethodDescriptor),
+		connect.WithHandlerOptions(opts...),
+	)
+	kVServiceWatchHandler := connect.NewServerStreamHandler(
+		KVServiceWatchProcedure,
+		svc.Watch,
+		connect.WithSchema(kVServiceWatchMethodDescriptor),
+		connect.WithHandlerOptions(opts...),
+	)
+	kVServiceStreamStateHandler := connect.NewServerStreamHandler(
+		KVServiceStreamStateProcedure,
+		svc.StreamState,
+		connect.WithSchema(kVServiceStreamStateMethodDescriptor),
+		connect.WithHandlerOptions(opts...),
+	)
+	kVServiceHandoffHandler := connect.NewUnaryHandler(
+		KVServiceHandoffProcedure,
+		svc.Handoff,
+		connect.WithSchema(kVServiceHandoffMethodDescriptor),
+		connect.WithHandlerOptions(opts...),
+	)
 	return "/internalpb.KVService/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		switch r.URL.Path {
 		case KVServicePutProcedure:
@@ -208,6 +308,14 @@ func NewKVServiceHandler(svc KVServiceHandler, opts ...connect.HandlerOption) (s
 			kVServiceKeyExistsHandler.ServeHTTP(w, r)
 		case KVServiceListProcedure:
 			kVServiceListHandler.ServeHTTP(w, r)
+		case KVServiceClusterInfoProcedure:
+			kVServiceClusterInfoHandler.ServeHTTP(w, r)
+		case KVServiceWatchProcedure:
+			kVServiceWatchHandler.ServeHTTP(w, r)
+		case KVServiceStreamStateProcedure:
+			kVServiceStreamStateHandler.ServeHTTP(w, r)
+		case KVServiceHandoffProcedure:
+			kVServiceHandoffHandler.ServeHTTP(w, r)
 		default:
 			http.NotFound(w, r)
 		}
@@ -236,3 +344,19 @@ func (UnimplementedKVServiceHandler) KeyExists(context.Context, *connect.Request
 func (UnimplementedKVServiceHandler) List(context.Context, *connect.Request[internalpb.ListRequest]) (*connect.Response[internalpb.ListResponse], error) {
 	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("internalpb.KVService.List is not implemented"))
 }
+
+func (UnimplementedKVServiceHandler) ClusterInfo(context.Context, *connect.Request[internalpb.ClusterInfoRequest]) (*connect.Response[internalpb.ClusterInfoResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("internalpb.KVService.ClusterInfo is not implemented"))
+}
+
+func (UnimplementedKVServiceHandler) Watch(context.Context, *connect.Request[internalpb.WatchRequest], *connect.ServerStream[internalpb.WatchResponse]) error {
+	return connect.NewError(connect.CodeUnimplemented, errors.New("internalpb.KVService.Watch is not implemented"))
+}
+
+func (UnimplementedKVServiceHandler) StreamState(context.Context, *connect.Request[internalpb.StreamStateRequest], *connect.ServerStream[internalpb.StreamStateResponse]) error {
+	return connect.NewError(connect.CodeUnimplemented, errors.New("internalpb.KVService.StreamState is not implemented"))
+}
+
+func (UnimplementedKVServiceHandler) Handoff(context.Context, *connect.Request[internalpb.HandoffRequest]) (*connect.Response[internalpb.HandoffResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("internalpb.KVService.Handoff is not implemented"))
+}
@@ -0,0 +1,340 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2024 Arsene Tochemey Gandote
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package gokv
+
+import (
+	"hash/fnv"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"go.uber.org/atomic"
+
+	"github.com/tochemey/gokv/internal/internalpb"
+)
+
+// localStoreShards is the number of shards of the node local state
+const localStoreShards = 32
+
+// localStore holds the node local state partitioned in shards so that the calls on
+// different keys do not contend on a single lock. The stored entries are never mutated,
+// a write replaces the entry, hence a snapshot of the state can be serialized without holding any lock
+type localStore struct {
+	nodeID string
+	shards [localStoreShards]*storeShard
+	// entries and size track the number of entries and the size of their keys and values.
+	// The removed entries are not counted so that they do not take room from the live ones
+	entries *atomic.Int64
+	size    *atomic.Int64
+	// generation identifies the lifetime of the node and version is increased on every change,
+	// they let the peers reject the stale pushes
+	generation uint64
+	version    *atomic.Uint64
+}
+
+// storeShard defines a partition of the node local state
+type storeShard struct {
+	sync.RWMutex
+	entries map[string]*internalpb.Entry
+	// access holds the access statistics of the keys used to pick the entries to evict
+	access map[string]*keyAccess
+}
+
+// newLocalStore creates an instance of localStore
+func newLocalStore(nodeID string) *localStore {
+	store := &localStore{
+		nodeID:     nodeID,
+		entries:    atomic.NewInt64(0),
+		size:       atomic.NewInt64(0),
+		generation: uint64(time.Now().UnixNano()),
+		version:    atomic.NewUint64(0),
+	}
+
+	for i := range store.shards {
+		store.shards[i] = &storeShard{
+			entries: make(map[string]*internalpb.Entry),
+			access:  make(map[string]*keyAccess),
+		}
+	}
+	return store
+}
+
+// shard returns the shard holding the given key
+func (store *localStore) shard(key string) *storeShard {
+	hasher := fnv.New32a()
+	_, _ = hasher.Write([]byte(key))
+	return store.shards[hasher.Sum32()%localStoreShards]
+}
+
+// get returns the entry of the given key and records the access
+func (store *localStore) get(key string) (*internalpb.Entry, bool) {
+	shard := store.shard(key)
+	shard.RLock()
+	entry, exists := shard.entries[key]
+	access := shard.access[key]
+	shard.RUnlock()
+
+	if exists && access != nil && visible(entry) {
+		access.touch()
+	}
+	return entry, exists
+}
+
+// peek returns the entry of the given key without recording the access
+func (store *localStore) peek(key string) (*internalpb.Entry, bool) {
+	shard := store.shard(key)
+	shard.RLock()
+	entry, exists := shard.entries[key]
+	shard.RUnlock()
+	return entry, exists
+}
+
+// put stores the given entry. It returns true when an existing key has been updated
+func (store *localStore) put(entry *internalpb.Entry) bool {
+	shard := store.shard(entry.GetKey())
+	shard.Lock()
+	defer shard.Unlock()
+	return store.putLocked(shard, entry)
+}
+
+// archive replaces the entry of the key of the given removed entry. It returns false, and nothing is stored,
+// when the key does not exist or has already been removed
+func (store *localStore) archive(tombstone *internalpb.Entry) bool {
+	shard := store.shard(tombstone.GetKey())
+	shard.Lock()
+	defer shard.Unlock()
+
+	if current, exists := shard.entries[tombstone.GetKey()]; !exists || current.GetArchived() {
+		return false
+	}
+	store.putLocked(shard, tombstone)
+	return true
+}
+
+// putLocked stores the given entry in the given shard. It returns true when an existing key has been updated.
+// The caller must hold the shard lock
+func (store *localStore) putLocked(shard *storeShard, entry *internalpb.Entry) bool {
+	key := entry.GetKey()
+	previous, exists := shard.entries[key]
+	updated := exists && visible(previous)
+	shard.entries[key] = entry
+
+	// the access statistics are not kept for a removed entry
+	if entry.GetArchived() {
+		delete(shard.access, key)
+	} else {
+		access, ok := shard.access[key]
+		if !ok || !updated {
+			access = newKeyAccess()
+			shard.access[key] = access
+		}
+		access.touch()
+	}
+
+	if exists {
+		store.uncount(key, previous)
+	}
+	store.count(key, entry)
+	store.version.Inc()
+	return updated
+}
+
+// remove removes the given key. It returns true when the key existed
+func (store *localStore) remove(key string) bool {
+	shard := store.shard(key)
+	shard.Lock()
+	defer shard.Unlock()
+	return store.removeLocked(shard, key)
+}
+
+// removeIf removes the given key when it still holds the given entry. It returns true when the key has been removed
+func (store *localStore) removeIf(key string, entry *internalpb.Entry) bool {
+	shard := store.shard(key)
+	shard.Lock()
+	defer shard.Unlock()
+
+	if current, exists := shard.entries[key]; !exists || current != entry {
+		return false
+	}
+	return store.removeLocked(shard, key)
+}
+
+// removeLocked removes the given key from the given shard. The caller must hold the shard lock
+func (store *localStore) removeLocked(shard *storeShard, key string) bool {
+	entry, exists := shard.entries[key]
+	if !exists {
+		return false
+	}
+
+	delete(shard.entries, key)
+	delete(shard.access, key)
+	store.uncount(key, entry)
+	store.version.Inc()
+	return true
+}
+
+// count adds the given entry to the entries and size counters unless it has been removed
+func (store *localStore) count(key string, entry *internalpb.Entry) {
+	if !entry.GetArchived() {
+		store.entries.Inc()
+		store.size.Add(entrySize(key, entry.GetValue()))
+	}
+}
+
+// uncount subtracts the given entry from the entries and size counters unless it has been removed
+func (store *localStore) uncount(key string, entry *internalpb.Entry) {
+	if !entry.GetArchived() {
+		store.entries.Dec()
+		store.size.Sub(entrySize(key, entry.GetValue()))
+	}
+}
+
+// removeExpired removes the expired entries and returns their number
+func (store *localStore) removeExpired() int {
+	return store.removeWhere(expired)
+}
+
+// removeTombstones removes the removed entries whose retention has elapsed and returns their number
+func (store *localStore) removeTombstones() int {
+	return store.removeWhere(func(entry *internalpb.Entry) bool {
+		return entry.GetArchived() && expired(entry)
+	})
+}
+
+// removeWhere removes the entries matching the given predicate and returns their number
+func (store *localStore) removeWhere(predicate func(entry *internalpb.Entry) bool) int {
+	removed := 0
+	for _, shard := range store.shards {
+		shard.Lock()
+		for key, entry := range shard.entries {
+			if predicate(entry) && store.removeLocked(shard, key) {
+				removed++
+			}
+		}
+		shard.Unlock()
+	}
+	return removed
+}
+
+// list returns the entries that have not expired
+func (store *localStore) list() []*internalpb.Entry {
+	entries := make([]*internalpb.Entry, 0, store.entries.Load())
+	for _, shard := range store.shards {
+		shard.RLock()
+		for _, entry := range shard.entries {
+			if !expired(entry) {
+				entries = append(entries, entry)
+			}
+		}
+		shard.RUnlock()
+	}
+	return entries
+}
+
+// snapshot returns the node state at a point in time.
+// Every shard is only locked while its entries are referenced. The version is read first
+// so that a change made during the snapshot is always carried by a later version
+func (store *localStore) snapshot() *internalpb.NodeState {
+	state := &internalpb.NodeState{
+		NodeId:     store.nodeID,
+		Entries:    make(map[string]*internalpb.Entry, store.entries.Load()),
+		Generation: store.generation,
+		Version:    store.version.Load(),
+	}
+
+	for _, shard := range store.shards {
+		shard.RLock()
+		for key, entry := range shard.entries {
+			state.Entries[key] = entry
+		}
+		shard.RUnlock()
+	}
+	return state
+}
+
+// candidates returns the entries that can be evicted to make room for the given key.
+// The removed entries are never evicted since they supersede the older entries of their key held by the peers
+func (store *localStore) candidates(key string) []*evictionCandidate {
+	candidates := make([]*evictionCandidate, 0, store.entries.Load())
+	for _, shard := range store.shards {
+		shard.RLock()
+		for k, entry := range shard.entries {
+			if k == key || entry.GetArchived() {
+				continue
+			}
+			candidates = append(candidates, newEvictionCandidate(shard, k, entry))
+		}
+		shard.RUnlock()
+	}
+	return candidates
+}
+
+// sample returns at most the given number of entries picked at random among the ones that can be evicted
+// to make room for the given key. Every pass over the shards, starting at a random one, takes an entry not
+// picked yet from every shard among its first entries, the shard iteration order being random.
+// Hence the local state is not walked as a whole and the sample holds all the entries of a small local state
+func (store *localStore) sample(key string, count int) []*evictionCandidate {
+	candidates := make([]*evictionCandidate, 0, count)
+	picked := make(map[string]struct{}, count)
+	offset := rand.IntN(localStoreShards)
+	for pass := 0; pass < count && len(candidates) < count; pass++ {
+		found := false
+		for i := 0; i < localStoreShards && len(candidates) < count; i++ {
+			shard := store.shards[(offset+i)%localStoreShards]
+			shard.RLock()
+			scanned := 0
+			for k, entry := range shard.entries {
+				if scanned++; scanned > count {
+					break
+				}
+
+				if _, ok := picked[k]; ok || k == key || entry.GetArchived() {
+					continue
+				}
+
+				picked[k] = struct{}{}
+				candidates = append(candidates, newEvictionCandidate(shard, k, entry))
+				found = true
+				break
+			}
+			shard.RUnlock()
+		}
+
+		if !found {
+			break
+		}
+	}
+	return candidates
+}
+
+// len returns the number of entries, the removed entries excluded
+func (store *localStore) len() int64 {
+	return store.entries.Load()
+}
+
+// bytes returns the size of the entries keys and values, the removed entries excluded
+func (store *localStore) bytes() int64 {
+	return store.size.Load()
+}
@@ -0,0 +1,159 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2024 Arsene Tochemey Gandote
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package gokv
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/tochemey/gokv/internal/internalpb"
+)
+
+func TestLocalStore(t *testing.T) {
+	newEntry := func(key, value string, expiration time.Duration) *internalpb.Entry {
+		return &internalpb.Entry{
+			Key:             key,
+			Value:           []byte(value),
+			LastUpdatedTime: timestamppb.Now(),
+			Expiry:          setExpiry(expiration),
+		}
+	}
+
+	t.Run("With put and remove", func(t *testing.T) {
+		store := newLocalStore("node")
+		assert.False(t, store.put(newEntry("key", "value", NoExpiration)))
+		assert.True(t, store.put(newEntry("key", "value2", NoExpiration)))
+		assert.EqualValues(t, 1, store.len())
+		assert.EqualValues(t, 9, store.bytes())
+
+		entry, ok := store.get("key")
+		require.True(t, ok)
+		assert.Equal(t, []byte("value2"), entry.GetValue())
+
+		assert.True(t, store.remove("key"))
+		assert.False(t, store.remove("key"))
+		assert.Zero(t, store.len())
+		assert.Zero(t, store.bytes())
+	})
+	t.Run("With expired entries", func(t *testing.T) {
+		store := newLocalStore("node")
+		store.put(newEntry("key1", "value", NoExpiration))
+		store.put(newEntry("key2", "value", time.Millisecond))
+		time.Sleep(10 * time.Millisecond)
+
+		assert.Len(t, store.list(), 1)
+		// an expired key is added again rather than updated
+		assert.False(t, store.put(newEntry("key2", "value", time.Millisecond)))
+		time.Sleep(10 * time.Millisecond)
+		assert.Equal(t, 1, store.removeExpired())
+		assert.EqualValues(t, 1, store.len())
+	})
+	t.Run("With tombstones", func(t *testing.T) {
+		store := newLocalStore("node")
+		store.put(newEntry("key1", "value", time.Millisecond))
+		tombstone := newEntry("key2", "", time.Millisecond)
+		tombstone.Archived = proto.Bool(true)
+		store.put(tombstone)
+		time.Sleep(10 * time.Millisecond)
+
+		// only the removed entries are purged
+		assert.Equal(t, 1, store.removeTombstones())
+		_, exists := store.peek("key2")
+		assert.False(t, exists)
+		_, exists = store.peek("key1")
+		assert.True(t, exists)
+	})
+	t.Run("With sample", func(t *testing.T) {
+		store := newLocalStore("node")
+		for i := 0; i < 1000; i++ {
+			store.put(newEntry(strconv.Itoa(i), "value", NoExpiration))
+		}
+
+		sample := store.sample("0", evictionSamples)
+		assert.Len(t, sample, evictionSamples)
+		picked := make(map[string]struct{}, len(sample))
+		for _, candidate := range sample {
+			assert.NotEqual(t, "0", candidate.key)
+			picked[candidate.key] = struct{}{}
+		}
+		assert.Len(t, picked, evictionSamples)
+
+		// a small local state is sampled as a whole, the removed entries excluded
+		store = newLocalStore("node")
+		store.put(newEntry("key1", "value", NoExpiration))
+		store.put(newEntry("key2", "value", NoExpiration))
+		tombstone := newEntry("key3", "", NoExpiration)
+		tombstone.Archived = proto.Bool(true)
+		store.put(tombstone)
+		assert.Len(t, store.sample("key", evictionSamples), 2)
+	})
+	t.Run("With snapshot", func(t *testing.T) {
+		store := newLocalStore("node")
+		for i := 0; i < 100; i++ {
+			store.put(newEntry(strconv.Itoa(i), "value", NoExpiration))
+		}
+
+		snapshot := store.snapshot()
+		store.put(newEntry("100", "value", NoExpiration))
+		assert.Equal(t, "node", snapshot.GetNodeId())
+		assert.Len(t, snapshot.GetEntries(), 100)
+		assert.EqualValues(t, 100, snapshot.GetVersion())
+		assert.NotZero(t, snapshot.GetGeneration())
+
+		// a missing key does not change the version
+		store.remove("unknown")
+		store.remove("100")
+		assert.EqualValues(t, 102, store.snapshot().GetVersion())
+	})
+	t.Run("With concurrent writes", func(t *testing.T) {
+		store := newLocalStore("node")
+		wg := new(sync.WaitGroup)
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func(worker int) {
+				defer wg.Done()
+				for j := 0; j < 100; j++ {
+					key := strconv.Itoa(worker*100 + j)
+					store.put(newEntry(key, "value", NoExpiration))
+					_ = store.snapshot()
+					if j%2 == 0 {
+						store.remove(key)
+					}
+				}
+			}(i)
+		}
+		wg.Wait()
+
+		assert.EqualValues(t, 500, store.len())
+		assert.Len(t, store.snapshot().GetEntries(), 500)
+	})
+}
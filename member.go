@@ -41,6 +41,8 @@ type Member struct {
 	Port          uint16
 	DiscoveryPort uint16
 	CreatedAt     time.Time
+	Tags          map[string]string
+	Zone          string
 }
 
 // DiscoveryAddress returns the member discoveryAddress
@@ -48,6 +50,23 @@ func (m *Member) DiscoveryAddress() string {
 	return net.JoinHostPort(m.Host, strconv.Itoa(int(m.DiscoveryPort)))
 }
 
+// Tag returns the value of the given tag and whether the member has it
+func (m *Member) Tag(key string) (string, bool) {
+	value, ok := m.Tags[key]
+	return value, ok
+}
+
+// PeerFilter defines a predicate used to filter the peers
+type PeerFilter func(member *Member) bool
+
+// HasTag returns a PeerFilter that selects the members having the given tag value
+func HasTag(key, value string) PeerFilter {
+	return func(member *Member) bool {
+		actual, ok := member.Tag(key)
+		return ok && actual == value
+	}
+}
+
 // memberFromMeta returns a Member record from
 // a node metadata
 func memberFromMeta(meta []byte) (*Member, error) {
@@ -55,11 +74,19 @@ func memberFromMeta(meta []byte) (*Member, error) {
 	if err := proto.Unmarshal(meta, nodeMeta); err != nil {
 		return nil, err
 	}
+	return memberFromNodeMeta(nodeMeta), nil
+}
+
+// memberFromNodeMeta returns a Member record from
+// a node metadata
+func memberFromNodeMeta(nodeMeta *internalpb.NodeMeta) *Member {
 	return &Member{
 		Name:          nodeMeta.GetName(),
 		Host:          nodeMeta.GetHost(),
 		Port:          uint16(nodeMeta.GetPort()),
 		DiscoveryPort: uint16(nodeMeta.GetDiscoveryPort()),
 		CreatedAt:     nodeMeta.GetCreationTime().AsTime(),
-	}, nil
+		Tags:          nodeMeta.GetTags(),
+		Zone:          nodeMeta.GetZone(),
+	}
 }
@@ -49,6 +49,24 @@ func TestMember(t *testing.T) {
 	assert.Equal(t, expected, member.DiscoveryAddress())
 }
 
+func TestMemberTags(t *testing.T) {
+	member := &Member{
+		Name: "name",
+		Tags: map[string]string{"zone": "a", "role": "cache"},
+	}
+
+	zone, ok := member.Tag("zone")
+	assert.True(t, ok)
+	assert.Equal(t, "a", zone)
+	_, ok = member.Tag("version")
+	assert.False(t, ok)
+
+	assert.True(t, HasTag("zone", "a")(member))
+	assert.False(t, HasTag("zone", "b")(member))
+	assert.False(t, HasTag("version", "1")(member))
+	assert.False(t, HasTag("zone", "a")(new(Member)))
+}
+
 func TestMemberFromMeta(t *testing.T) {
 	// caution make sure to set the time to UTC
 	// then the reflect.DeepEqual will work
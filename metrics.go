@@ -0,0 +1,194 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2024 Arsene Tochemey Gandote
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package gokv
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+)
+
+const (
+	// instrumentationName defines the name of the meter
+	instrumentationName = "github.com/tochemey/gokv"
+
+	// pushOperation defines the push/pull operation attribute value for LocalState
+	pushOperation = "push"
+	// pullOperation defines the push/pull operation attribute value for MergeRemoteState
+	pullOperation = "pull"
+	// transferOperation defines the push/pull operation attribute value for the transfer of a peer state
+	transferOperation = "transfer"
+
+	// invalidStateReason defines the rejection reason of a peer state that cannot be decoded
+	invalidStateReason = "invalid"
+	// staleStateReason defines the rejection reason of a peer state older than the merged one
+	staleStateReason = "stale"
+)
+
+var (
+	// operationKey defines the push/pull operation attribute key
+	operationKey = attribute.Key("operation")
+	// policyKey defines the eviction policy attribute key
+	policyKey = attribute.Key("policy")
+	// reasonKey defines the push/pull rejection reason attribute key
+	reasonKey = attribute.Key("reason")
+)
+
+// metrics defines the node metrics instruments
+type metrics struct {
+	// pushPullSize records the size in bytes of the state exchanged during push/pull
+	pushPullSize metric.Int64Histogram
+	// pushPullDuration records the duration in seconds of a push/pull operation
+	pushPullDuration metric.Float64Histogram
+	// pushPullRejected counts the peers states rejected during a push/pull
+	pushPullRejected metric.Int64Counter
+	// evictions counts the entries removed by the cleaner job
+	evictions metric.Int64Counter
+	// storeEvictions counts the entries evicted to make room for new writes
+	storeEvictions metric.Int64Counter
+}
+
+// noopMetrics returns a metrics instance that records nothing
+func noopMetrics() *metrics {
+	m, _ := newMetrics(noop.NewMeterProvider())
+	return m
+}
+
+// newMetrics creates an instance of metrics using the given meter provider
+func newMetrics(provider metric.MeterProvider) (*metrics, error) {
+	meter := provider.Meter(instrumentationName)
+	pushPullSize, err := meter.Int64Histogram("gokv.pushpull.size",
+		metric.WithDescription("The size of the state exchanged during a push/pull"),
+		metric.WithUnit("By"))
+	if err != nil {
+		return nil, err
+	}
+
+	pushPullDuration, err := meter.Float64Histogram("gokv.pushpull.duration",
+		metric.WithDescription("The duration of a push/pull operation"),
+		metric.WithUnit("s"))
+	if err != nil {
+		return nil, err
+	}
+
+	pushPullRejected, err := meter.Int64Counter("gokv.pushpull.rejected",
+		metric.WithDescription("The number of peers states rejected during a push/pull"),
+		metric.WithUnit("{state}"))
+	if err != nil {
+		return nil, err
+	}
+
+	evictions, err := meter.Int64Counter("gokv.cleaner.evictions",
+		metric.WithDescription("The number of expired entries removed by the cleaner"),
+		metric.WithUnit("{entry}"))
+	if err != nil {
+		return nil, err
+	}
+
+	storeEvictions, err := meter.Int64Counter("gokv.store.evictions",
+		metric.WithDescription("The number of entries evicted to make room for new writes"),
+		metric.WithUnit("{entry}"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &metrics{
+		pushPullSize:     pushPullSize,
+		pushPullDuration: pushPullDuration,
+		pushPullRejected: pushPullRejected,
+		evictions:        evictions,
+		storeEvictions:   storeEvictions,
+	}, nil
+}
+
+// recordPushPull records the size and duration of a push/pull operation
+func (m *metrics) recordPushPull(operation string, size int, start time.Time) {
+	ctx := context.Background()
+	attrs := metric.WithAttributes(operationKey.String(operation))
+	m.pushPullSize.Record(ctx, int64(size), attrs)
+	m.pushPullDuration.Record(ctx, time.Since(start).Seconds(), attrs)
+}
+
+// recordRejectedState records a peer state rejected for the given reason
+func (m *metrics) recordRejectedState(reason string) {
+	m.pushPullRejected.Add(context.Background(), 1, metric.WithAttributes(reasonKey.String(reason)))
+}
+
+// recordEvictions records the number of entries evicted by the given policy
+func (m *metrics) recordEvictions(policy EvictionPolicy, evicted int) {
+	m.storeEvictions.Add(context.Background(), int64(evicted), metric.WithAttributes(policyKey.String(policy.String())))
+}
+
+// registerNodeMetrics registers the node observable instruments
+func registerNodeMetrics(provider metric.MeterProvider, node *Node) (metric.Registration, error) {
+	meter := provider.Meter(instrumentationName)
+	localEntries, err := meter.Int64ObservableGauge("gokv.local.entries",
+		metric.WithDescription("The number of entries in the node local state"),
+		metric.WithUnit("{entry}"))
+	if err != nil {
+		return nil, err
+	}
+
+	localBytes, err := meter.Int64ObservableGauge("gokv.local.bytes",
+		metric.WithDescription("The size of the keys and values of the node local state"),
+		metric.WithUnit("By"))
+	if err != nil {
+		return nil, err
+	}
+
+	peersEntries, err := meter.Int64ObservableGauge("gokv.peers.entries",
+		metric.WithDescription("The number of entries in the peers state held by the node"),
+		metric.WithUnit("{entry}"))
+	if err != nil {
+		return nil, err
+	}
+
+	peersBytes, err := meter.Int64ObservableGauge("gokv.peers.bytes",
+		metric.WithDescription("The size of the peers state held by the node"),
+		metric.WithUnit("By"))
+	if err != nil {
+		return nil, err
+	}
+
+	members, err := meter.Int64ObservableGauge("gokv.members",
+		metric.WithDescription("The number of members in the cluster"),
+		metric.WithUnit("{member}"))
+	if err != nil {
+		return nil, err
+	}
+
+	return meter.RegisterCallback(func(_ context.Context, observer metric.Observer) error {
+		stats := node.delegate.stats()
+		observer.ObserveInt64(localEntries, int64(stats.localEntries))
+		observer.ObserveInt64(localBytes, int64(stats.localBytes))
+		observer.ObserveInt64(peersEntries, int64(stats.peersEntries))
+		observer.ObserveInt64(peersBytes, int64(stats.peersBytes))
+		observer.ObserveInt64(members, int64(node.numMembers()))
+		return nil
+	}, localEntries, localBytes, peersEntries, peersBytes, members)
+}
@@ -0,0 +1,201 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2024 Arsene Tochemey Gandote
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package gokv
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/atomic"
+)
+
+// nearCacheRetryInterval is the time to wait before watching again the node
+// after the near cache invalidation stream ended
+const nearCacheRetryInterval = time.Second
+
+// bypassNearCacheKey is the context key set by BypassNearCache
+type bypassNearCacheKey struct{}
+
+// BypassNearCache returns a copy of the given context making the Client
+// read from the cluster instead of its near cache
+func BypassNearCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, bypassNearCacheKey{}, true)
+}
+
+// nearCacheBypassed returns true when the given context bypasses the near cache
+func nearCacheBypassed(ctx context.Context) bool {
+	bypass, _ := ctx.Value(bypassNearCacheKey{}).(bool)
+	return bypass
+}
+
+// NearCacheStats defines the Client near cache statistics
+type NearCacheStats struct {
+	// Hits is the number of reads served by the near cache
+	Hits uint64
+	// Misses is the number of reads served by the cluster
+	Misses uint64
+	// Entries is the number of entries held by the near cache
+	Entries int
+}
+
+// nearCacheItem defines an entry held by the near cache
+type nearCacheItem struct {
+	entry     *Entry
+	expiresAt time.Time
+}
+
+// nearCache is a size and TTL bounded in-process cache of the entries read by the Client.
+// It only serves reads while the invalidation stream is established
+type nearCache struct {
+	mu         sync.Mutex
+	items      map[string]*list.Element
+	recency    *list.List
+	maxEntries int
+	ttl        time.Duration
+	ready      bool
+	// generation changes on every invalidation so that a read racing
+	// with an invalidation does not cache a stale entry
+	generation uint64
+	hits       *atomic.Uint64
+	misses     *atomic.Uint64
+}
+
+// newNearCache creates an instance of nearCache
+func newNearCache(maxEntries int, ttl time.Duration) *nearCache {
+	return &nearCache{
+		items:      make(map[string]*list.Element, maxEntries),
+		recency:    list.New(),
+		maxEntries: maxEntries,
+		ttl:        ttl,
+		hits:       atomic.NewUint64(0),
+		misses:     atomic.NewUint64(0),
+	}
+}
+
+// get returns a copy of the cached entry of the given key
+func (cache *nearCache) get(key string) (*Entry, bool) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	element, ok := cache.items[key]
+	if !ok || !cache.ready {
+		cache.misses.Inc()
+		return nil, false
+	}
+
+	item := element.Value.(*nearCacheItem)
+	if !item.expiresAt.IsZero() && time.Now().After(item.expiresAt) {
+		cache.remove(element)
+		cache.misses.Inc()
+		return nil, false
+	}
+
+	cache.recency.MoveToFront(element)
+	cache.hits.Inc()
+	return item.entry.clone(), true
+}
+
+// version returns the current invalidation generation
+func (cache *nearCache) version() uint64 {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	return cache.generation
+}
+
+// set caches the given entry read at the given generation.
+// Nothing is cached when an invalidation happened since or when the invalidation stream is not established
+func (cache *nearCache) set(generation uint64, entry *Entry) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if !cache.ready || generation != cache.generation {
+		return
+	}
+
+	item := &nearCacheItem{
+		entry:     entry.clone(),
+		expiresAt: entry.ExpiresAt,
+	}
+
+	if cache.ttl > 0 {
+		deadline := time.Now().Add(cache.ttl)
+		if item.expiresAt.IsZero() || deadline.Before(item.expiresAt) {
+			item.expiresAt = deadline
+		}
+	}
+
+	if element, ok := cache.items[entry.Key]; ok {
+		element.Value = item
+		cache.recency.MoveToFront(element)
+		return
+	}
+
+	cache.items[entry.Key] = cache.recency.PushFront(item)
+	for cache.recency.Len() > cache.maxEntries {
+		cache.remove(cache.recency.Back())
+	}
+}
+
+// invalidate removes the given key from the cache
+func (cache *nearCache) invalidate(key string) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	cache.generation++
+	if element, ok := cache.items[key]; ok {
+		cache.remove(element)
+	}
+}
+
+// reset empties the cache and sets whether it serves the reads
+func (cache *nearCache) reset(ready bool) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	cache.generation++
+	cache.ready = ready
+	cache.items = make(map[string]*list.Element, cache.maxEntries)
+	cache.recency.Init()
+}
+
+// stats returns the cache statistics
+func (cache *nearCache) stats() NearCacheStats {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	return NearCacheStats{
+		Hits:    cache.hits.Load(),
+		Misses:  cache.misses.Load(),
+		Entries: len(cache.items),
+	}
+}
+
+// remove removes the given element from the cache.
+// The caller must hold the cache lock
+func (cache *nearCache) remove(element *list.Element) {
+	cache.recency.Remove(element)
+	delete(cache.items, element.Value.(*nearCacheItem).entry.Key)
+}
@@ -0,0 +1,115 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2024 Arsene Tochemey Gandote
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package gokv
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNearCache(t *testing.T) {
+	newEntry := func(key string) *Entry {
+		return &Entry{
+			Key:         key,
+			Value:       []byte("value"),
+			LastUpdated: time.Now(),
+		}
+	}
+
+	t.Run("With a stream not established", func(t *testing.T) {
+		cache := newNearCache(10, time.Minute)
+		cache.set(cache.version(), newEntry("key"))
+		_, ok := cache.get("key")
+		assert.False(t, ok)
+		assert.Equal(t, NearCacheStats{Misses: 1}, cache.stats())
+	})
+	t.Run("With max entries", func(t *testing.T) {
+		cache := newNearCache(2, 0)
+		cache.reset(true)
+		cache.set(cache.version(), newEntry("key1"))
+		cache.set(cache.version(), newEntry("key2"))
+		// key1 becomes the most recently used
+		_, ok := cache.get("key1")
+		require.True(t, ok)
+		cache.set(cache.version(), newEntry("key3"))
+
+		_, ok = cache.get("key2")
+		assert.False(t, ok)
+		entry, ok := cache.get("key1")
+		require.True(t, ok)
+		assert.Equal(t, "key1", entry.Key)
+		assert.Equal(t, NearCacheStats{Hits: 2, Misses: 1, Entries: 2}, cache.stats())
+	})
+	t.Run("With ttl", func(t *testing.T) {
+		cache := newNearCache(10, 10*time.Millisecond)
+		cache.reset(true)
+		cache.set(cache.version(), newEntry("key"))
+		_, ok := cache.get("key")
+		require.True(t, ok)
+
+		time.Sleep(20 * time.Millisecond)
+		_, ok = cache.get("key")
+		assert.False(t, ok)
+		assert.Zero(t, cache.stats().Entries)
+	})
+	t.Run("With entry expiry", func(t *testing.T) {
+		cache := newNearCache(10, time.Minute)
+		cache.reset(true)
+		entry := newEntry("key")
+		entry.ExpiresAt = time.Now().Add(10 * time.Millisecond)
+		cache.set(cache.version(), entry)
+
+		cached, ok := cache.get("key")
+		require.True(t, ok)
+		assert.Positive(t, cached.TTL)
+
+		time.Sleep(20 * time.Millisecond)
+		_, ok = cache.get("key")
+		assert.False(t, ok)
+	})
+	t.Run("With invalidation", func(t *testing.T) {
+		cache := newNearCache(10, time.Minute)
+		cache.reset(true)
+		cache.set(cache.version(), newEntry("key"))
+		cache.invalidate("key")
+		_, ok := cache.get("key")
+		assert.False(t, ok)
+
+		// a read racing with an invalidation is not cached
+		version := cache.version()
+		cache.invalidate("key")
+		cache.set(version, newEntry("key"))
+		_, ok = cache.get("key")
+		assert.False(t, ok)
+	})
+	t.Run("With bypass", func(t *testing.T) {
+		assert.False(t, nearCacheBypassed(context.Background()))
+		assert.True(t, nearCacheBypassed(BypassNearCache(context.Background())))
+	})
+}
@@ -32,14 +32,20 @@ import (
 	"net"
 	nethttp "net/http"
 	"runtime"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"connectrpc.com/connect"
+	"connectrpc.com/otelconnect"
+	"github.com/flowchartsman/retry"
 	"github.com/hashicorp/memberlist"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/atomic"
+	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
 	"github.com/tochemey/gokv/internal/errorschain"
@@ -70,17 +76,37 @@ type Node struct {
 
 	// states whether the actor system has started or not
 	started *atomic.Bool
+	// states whether the node is handing off its entries before leaving the cluster
+	draining *atomic.Bool
 
 	httpServer *nethttp.Server
 	mu         *sync.Mutex
 
 	clusterClient      *Client
-	eventsChan         chan *Event
 	stopEventsListener chan struct{}
-	eventsLock         *sync.Mutex
+	stopRediscovery    chan struct{}
+	// stopTombstonesPurge stops the purge of the removed entries
+	stopTombstonesPurge chan struct{}
+	// transferClient is the http client used to transfer the states and the entries handed off with the peers
+	transferClient *nethttp.Client
+
+	// events is the subscription backing Events
+	events *Subscription
+	// subscriptions holds the cluster events subscriptions and watchers the Watch streams watchers.
+	// Both are replaced under the events lock on every change, so that the writes publish their
+	// events and changes to a snapshot without holding any lock
+	subscriptions  *atomic.Pointer[[]*Subscription]
+	eventsClosed   bool
+	watchers       *atomic.Pointer[[]*watcher]
+	watchersClosed bool
+	eventsLock     *sync.Mutex
 
 	discoveryAddress string
 	cleaner          *cleaner
+
+	metrics             *metrics
+	metricsRegistration metric.Registration
+	tracer              trace.Tracer
 }
 
 // newNode creates an instance of Node
@@ -122,23 +148,55 @@ func newNode(config *Config) (*Node, error) {
 		Port:          uint32(config.port),
 		DiscoveryPort: uint32(config.discoveryPort),
 		CreationTime:  timestamppb.New(time.Now().UTC()),
+		Zone:          config.zone,
+	}
+
+	metrics := noopMetrics()
+	if config.meterProvider != nil {
+		var err error
+		if metrics, err = newMetrics(config.meterProvider); err != nil {
+			return nil, fmt.Errorf("failed to create the node metrics: %w", err)
+		}
 	}
 
 	discoveryAddr := lib.HostPort(config.host, int(config.discoveryPort))
-	delegate := newDelegate(discoveryAddr, meta)
+	tracer := newTracer(config.tracerProvider)
+	delegate := newDelegate(discoveryAddr, meta, metrics, tracer)
+	if err := delegate.setTags(config.tags); err != nil {
+		return nil, err
+	}
+	delegate.limits = storageLimits{
+		maxEntries:   config.maxEntries,
+		maxTotalSize: config.maxTotalSize,
+		policy:       config.evictionPolicy,
+	}
+	delegate.tombstoneRetention = config.tombstoneRetention
 	mconfig.Delegate = delegate
 
 	node := &Node{
-		mu:                 new(sync.Mutex),
-		delegate:           delegate,
-		memberConfig:       mconfig,
-		started:            atomic.NewBool(false),
-		eventsChan:         make(chan *Event, 1),
-		stopEventsListener: make(chan struct{}, 1),
-		eventsLock:         new(sync.Mutex),
-		config:             config,
-		discoveryAddress:   discoveryAddr,
+		mu:                  new(sync.Mutex),
+		delegate:            delegate,
+		memberConfig:        mconfig,
+		started:             atomic.NewBool(false),
+		draining:            atomic.NewBool(false),
+		stopEventsListener:  make(chan struct{}, 1),
+		stopRediscovery:     make(chan struct{}),
+		stopTombstonesPurge: make(chan struct{}),
+		subscriptions:       atomic.NewPointer(new([]*Subscription)),
+		eventsLock:          new(sync.Mutex),
+		watchers:            atomic.NewPointer(new([]*watcher)),
+		transferClient:      http.NewClient(nil),
+		config:              config,
+		discoveryAddress:    discoveryAddr,
+		metrics:             metrics,
+		tracer:              tracer,
+	}
+
+	node.events = node.Subscribe()
+	delegate.onChanges = func(changes []*keyChange) {
+		node.notifyWatchers(changes...)
 	}
+	delegate.fetchState = node.fetchState
 
 	if config.cleanerJobInterval > 0 {
 		runCleaner(node, config.cleanerJobInterval)
@@ -151,6 +209,14 @@ func newNode(config *Config) (*Node, error) {
 // Start starts the cluster node
 func (node *Node) Start(ctx context.Context) error {
 	node.mu.Lock()
+
+	// create enough buffer to house the cluster events
+	// the events delegate needs to be set before the memberlist creation
+	eventsCh := make(chan memberlist.NodeEvent, defaultEventsBufferSize)
+	node.memberConfig.Events = &memberlist.ChannelEventDelegate{
+		Ch: eventsCh,
+	}
+
 	if err := errorschain.
 		New(errorschain.ReturnFirst()).
 		AddError(node.config.Validate()).
@@ -158,45 +224,71 @@ func (node *Node) Start(ctx context.Context) error {
 		AddError(node.config.provider.Register()).
 		AddError(node.join()).
 		AddError(node.serve(ctx)).
+		AddError(node.registerMetrics()).
 		Error(); err != nil {
 		node.mu.Unlock()
 		return err
 	}
 
-	// create enough buffer to house the cluster events
-	// TODO: revisit this number
-	eventsCh := make(chan memberlist.NodeEvent, 256)
-	node.memberConfig.Events = &memberlist.ChannelEventDelegate{
-		Ch: eventsCh,
-	}
-	node.clusterClient = NewClient(node.config.host, int(node.config.port))
+	node.clusterClient = NewClient(node.config.host, int(node.config.port),
+		WithClientInterceptors(node.config.interceptors...),
+		WithClientMetrics(node.config.meterProvider),
+		WithClientTracing(node.config.tracerProvider))
 	node.started.Store(true)
 	node.mu.Unlock()
 
 	// start listening to events
 	go node.eventsListener(eventsCh)
+	// purge the removed entries once their retention has elapsed
+	go node.purgeTombstones()
 
 	node.config.logger.Infof("%s successfully started", node.discoveryAddress)
 	return nil
 }
 
-// Stop stops gracefully the cluster node
+// Stop stops gracefully the cluster node.
+// When the drain on stop is enabled the node first hands off its entries to its peers, see Drain.
+// The handoff is bounded by the shutdown timeout
 func (node *Node) Stop(ctx context.Context) error {
+	if node.config.drainOnStop && node.started.Load() {
+		handoffCtx, cancel := context.WithTimeout(ctx, node.config.shutdownTimeout)
+		err := node.handoff(handoffCtx)
+		cancel()
+		if err != nil {
+			node.config.logger.Error(fmt.Errorf("%s failed to hand off its entries: %w", node.discoveryAddress, err))
+		}
+	}
+	return node.stop(ctx)
+}
+
+// stop leaves the cluster and releases the node resources
+// The node lock is released before the network calls
+func (node *Node) stop(ctx context.Context) error {
 	node.mu.Lock()
-	defer node.mu.Unlock()
 
 	// no-op when the node has not started
 	if !node.started.Load() {
+		node.mu.Unlock()
 		return nil
 	}
 
 	// no matter the outcome the node is officially off
 	node.started.Store(false)
-	ctx, cancel := context.WithTimeout(ctx, node.config.shutdownTimeout)
-	defer cancel()
 
 	// stop the events loop
 	close(node.stopEventsListener)
+	// end the Watch streams
+	node.closeWatchers()
+	// stop the peers rediscovery
+	close(node.stopRediscovery)
+	// stop the removed entries purge
+	close(node.stopTombstonesPurge)
+	// abort the states transfers, the fetched and the served ones
+	node.delegate.stopTransfers()
+	node.mu.Unlock()
+
+	// let the peers know this is a graceful leave
+	node.announceLeave(ctx)
 
 	if err := errorschain.
 		New(errorschain.ReturnFirst()).
@@ -205,7 +297,8 @@ func (node *Node) Stop(ctx context.Context) error {
 		AddError(node.config.provider.Deregister()).
 		AddError(node.config.provider.Close()).
 		AddError(node.memberlist.Shutdown()).
-		AddError(node.httpServer.Shutdown(ctx)).
+		AddError(node.shutdownServer(ctx)).
+		AddError(node.unregisterMetrics()).
 		Error(); err != nil {
 		node.config.logger.Error(fmt.Errorf("%s failed to stop: %w", node.discoveryAddress, err))
 		return err
@@ -214,56 +307,164 @@ func (node *Node) Stop(ctx context.Context) error {
 	return nil
 }
 
+// shutdownServer gracefully shuts down the http server. The shutdown has its own
+// shutdown timeout so that it is not starved by leaving the cluster
+func (node *Node) shutdownServer(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, node.config.shutdownTimeout)
+	defer cancel()
+	return node.httpServer.Shutdown(ctx)
+}
+
 // Put is used to distribute a key/value pair across a cluster of nodes
 // nolint
 func (node *Node) Put(ctx context.Context, request *connect.Request[internalpb.PutRequest]) (*connect.Response[internalpb.PutResponse], error) {
-	node.mu.Lock()
 	if !node.started.Load() {
-		node.mu.Unlock()
 		return nil, connect.NewError(connect.CodeFailedPrecondition, ErrNodeNotStarted)
 	}
 
+	if node.draining.Load() {
+		return nil, connect.NewError(connect.CodeUnavailable, ErrNodeDraining)
+	}
+
 	req := request.Msg
-	node.delegate.Put(req.GetKey(), req.GetValue(), req.GetExpiry().AsDuration())
-	node.mu.Unlock()
+	node.annotate(ctx, req.GetKey())
+	if err := node.checkLimits(req.GetKey(), req.GetValue()); err != nil {
+		return nil, err
+	}
 
+	result, err := node.delegate.Put(ctx, req.GetKey(), req.GetValue(), req.GetExpiry().AsDuration())
+	if err != nil {
+		return nil, limitViolation(connect.CodeResourceExhausted, err)
+	}
+
+	node.notifyPut(result)
 	return connect.NewResponse(new(internalpb.PutResponse)), nil
 }
 
-// Get is used to retrieve a key/value pair in a cluster of nodes
+// notifyPut publishes the key events and notifies the watchers of a write to the node local state
+func (node *Node) notifyPut(result *putResult) {
+	if len(result.evicted) > 0 {
+		node.metrics.recordEvictions(node.config.evictionPolicy, len(result.evicted))
+	}
+
+	for _, key := range result.evicted {
+		node.publishKeyEvent(KeyEvicted, key)
+		node.notifyWatchers(&keyChange{key: key})
+	}
+
+	key := result.entry.GetKey()
+	// a removed entry handed off by a draining peer deletes the key
+	if result.entry.GetArchived() {
+		if result.updated {
+			node.publishKeyEvent(KeyDeleted, key)
+			node.notifyWatchers(&keyChange{key: key})
+		}
+		return
+	}
+
+	eventType := KeyAdded
+	if result.updated {
+		eventType = KeyUpdated
+	}
+	node.publishKeyEvent(eventType, key)
+	node.notifyWatchers(&keyChange{key: key, entry: result.entry})
+}
+
+// checkLimits returns an error when the given key or value exceeds the node maximum sizes
+func (node *Node) checkLimits(key string, value []byte) error {
+	if node.config.maxKeySize > 0 && len(key) > node.config.maxKeySize {
+		return limitViolation(connect.CodeInvalidArgument, ErrKeyTooLarge)
+	}
+
+	if node.config.maxValueSize > 0 && len(value) > node.config.maxValueSize {
+		return limitViolation(connect.CodeInvalidArgument, ErrValueTooLarge)
+	}
+	return nil
+}
+
+// limitErrors maps the node storage limits to the errors returned when they are violated
+var limitErrors = map[internalpb.Limit]error{
+	internalpb.Limit_LIMIT_KEY_SIZE:   ErrKeyTooLarge,
+	internalpb.Limit_LIMIT_VALUE_SIZE: ErrValueTooLarge,
+	internalpb.Limit_LIMIT_ENTRIES:    ErrTooManyEntries,
+	internalpb.Limit_LIMIT_TOTAL_SIZE: ErrStoreFull,
+}
+
+// limitViolation returns the connect error of the given write error.
+// A violated storage limit is attached as a LimitViolation detail for the clients to return its typed error
+func limitViolation(code connect.Code, err error) error {
+	connectErr := connect.NewError(code, err)
+	for limit, limitErr := range limitErrors {
+		if !errors.Is(err, limitErr) {
+			continue
+		}
+
+		if detail, detailErr := connect.NewErrorDetail(&internalpb.LimitViolation{Limit: limit}); detailErr == nil {
+			connectErr.AddDetail(detail)
+		}
+		break
+	}
+	return connectErr
+}
+
+// Get is used to retrieve a key/value pair in a cluster of nodes.
+// The metadata describing the source and the freshness of the entry is returned when requested
 // nolint
 func (node *Node) Get(ctx context.Context, request *connect.Request[internalpb.GetRequest]) (*connect.Response[internalpb.GetResponse], error) {
-	node.mu.Lock()
-	if !node.started.Load() {
-		node.mu.Unlock()
-		return nil, connect.NewError(connect.CodeFailedPrecondition, ErrNodeNotStarted)
-	}
+	var (
+		entry    *internalpb.Entry
+		metadata *internalpb.EntryMetadata
+	)
+	if err := node.withReadTimeout(ctx, func(ctx context.Context) error {
+		if !node.started.Load() {
+			return connect.NewError(connect.CodeFailedPrecondition, ErrNodeNotStarted)
+		}
 
-	req := request.Msg
-	entry, err := node.delegate.Get(req.GetKey())
-	if err != nil {
-		node.mu.Unlock()
-		return nil, connect.NewError(connect.CodeNotFound, err)
+		req := request.Msg
+		node.annotate(ctx, req.GetKey())
+		var err error
+		if req.GetWithMetadata() {
+			entry, metadata, err = node.delegate.GetWithMetadata(ctx, req.GetKey())
+		} else {
+			entry, err = node.delegate.Get(ctx, req.GetKey())
+		}
+		if err != nil {
+			return connect.NewError(connect.CodeNotFound, err)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
 	}
 
-	node.mu.Unlock()
 	return connect.NewResponse(&internalpb.GetResponse{
-		Entry: entry,
+		Entry:    entry,
+		Metadata: metadata,
 	}), nil
 }
 
 // Delete is used to remove a key/value pair from a cluster of nodes
 // nolint
 func (node *Node) Delete(ctx context.Context, request *connect.Request[internalpb.DeleteRequest]) (*connect.Response[internalpb.DeleteResponse], error) {
-	node.mu.Lock()
 	if !node.started.Load() {
-		node.mu.Unlock()
 		return nil, connect.NewError(connect.CodeFailedPrecondition, ErrNodeNotStarted)
 	}
 
+	if node.draining.Load() {
+		return nil, connect.NewError(connect.CodeUnavailable, ErrNodeDraining)
+	}
+
 	req := request.Msg
-	node.delegate.Delete(req.GetKey())
-	node.mu.Unlock()
+	node.annotate(ctx, req.GetKey())
+	if err := node.checkLimits(req.GetKey(), nil); err != nil {
+		return nil, err
+	}
+
+	deleted := node.delegate.Delete(ctx, req.GetKey())
+
+	if deleted {
+		node.publishKeyEvent(KeyDeleted, req.GetKey())
+		node.notifyWatchers(&keyChange{key: req.GetKey()})
+	}
 
 	return connect.NewResponse(new(internalpb.DeleteResponse)), nil
 }
@@ -271,30 +472,64 @@ func (node *Node) Delete(ctx context.Context, request *connect.Request[internalp
 // KeyExists is used to check the existence of a given key in the cluster
 // nolint
 func (node *Node) KeyExists(ctx context.Context, request *connect.Request[internalpb.KeyExistsRequest]) (*connect.Response[internalpb.KeyExistResponse], error) {
-	node.mu.Lock()
 	if !node.started.Load() {
-		node.mu.Unlock()
 		return nil, connect.NewError(connect.CodeFailedPrecondition, ErrNodeNotStarted)
 	}
 
 	req := request.Msg
-	exists := node.delegate.Exists(req.GetKey())
-	node.mu.Unlock()
+	node.annotate(ctx, req.GetKey())
+	exists := node.delegate.Exists(ctx, req.GetKey())
 	return connect.NewResponse(&internalpb.KeyExistResponse{Exists: exists}), nil
 }
 
-// List returns the list of all entries at a given point in time
+// List returns the list of all entries at a given point in time.
+// The metadata of the entries is returned when requested
 // nolint
 func (node *Node) List(ctx context.Context, request *connect.Request[internalpb.ListRequest]) (*connect.Response[internalpb.ListResponse], error) {
-	node.mu.Lock()
-	if !node.started.Load() {
-		node.mu.Unlock()
-		return nil, connect.NewError(connect.CodeFailedPrecondition, ErrNodeNotStarted)
+	var (
+		entries  []*internalpb.Entry
+		metadata map[string]*internalpb.EntryMetadata
+	)
+	if err := node.withReadTimeout(ctx, func(ctx context.Context) error {
+		if !node.started.Load() {
+			return connect.NewError(connect.CodeFailedPrecondition, ErrNodeNotStarted)
+		}
+
+		trace.SpanFromContext(ctx).SetAttributes(nodeAttribute.String(node.discoveryAddress))
+		if request.Msg.GetWithMetadata() {
+			entries, metadata = node.delegate.ListWithMetadata(ctx)
+		} else {
+			entries = node.delegate.List(ctx)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
 	}
 
-	entries := node.delegate.List()
-	node.mu.Unlock()
-	return connect.NewResponse(&internalpb.ListResponse{Entries: entries}), nil
+	return connect.NewResponse(&internalpb.ListResponse{Entries: entries, Metadata: metadata}), nil
+}
+
+// withReadTimeout runs the given read and fails with a DeadlineExceeded error
+// when the read does not complete within the configured read timeout
+func (node *Node) withReadTimeout(ctx context.Context, read func(ctx context.Context) error) error {
+	if node.config.readTimeout <= 0 {
+		return read(ctx)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, node.config.readTimeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- read(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return connect.NewError(connect.CodeDeadlineExceeded, ctx.Err())
+	}
 }
 
 // Client returns the cluster Client
@@ -307,36 +542,178 @@ func (node *Node) Client() *Client {
 
 // Events returns a channel where cluster events are published
 func (node *Node) Events() <-chan *Event {
+	return node.events.Events()
+}
+
+// Subscribe creates a subscription to the cluster events.
+// Every subscription has its own bounded buffer and drop policy so that
+// slow subscribers do not block the node or the other subscribers.
+// The subscription is closed when the node stops.
+func (node *Node) Subscribe(opts ...SubscriptionOption) *Subscription {
+	subscription := newSubscription(opts...)
+	node.eventsLock.Lock()
+	if node.eventsClosed {
+		subscription.close()
+	} else {
+		subscriptions := append(slices.Clip(*node.subscriptions.Load()), subscription)
+		node.subscriptions.Store(&subscriptions)
+	}
+	node.eventsLock.Unlock()
+	return subscription
+}
+
+// Unsubscribe cancels the given subscription and closes its events channel
+func (node *Node) Unsubscribe(subscription *Subscription) {
 	node.eventsLock.Lock()
-	ch := node.eventsChan
+	subscriptions := slices.DeleteFunc(slices.Clone(*node.subscriptions.Load()), func(current *Subscription) bool {
+		return current == subscription
+	})
+	node.subscriptions.Store(&subscriptions)
 	node.eventsLock.Unlock()
-	return ch
+	subscription.close()
 }
 
 // HostPort returns the node host:port address
 func (node *Node) HostPort() string {
+	return node.discoveryAddress
+}
+
+// Replicas returns the cluster members holding the replicas of the given key, including the node itself,
+// as placed by the configured PlacementStrategy. The members are ordered by preference.
+// Use PreferZone to read from a same-zone replica first.
+func (node *Node) Replicas(key string, replicas int) ([]*Member, error) {
+	if !node.started.Load() {
+		return nil, ErrNodeNotStarted
+	}
+
+	peers, err := node.Peers()
+	if err != nil {
+		return nil, err
+	}
+
+	members := append(peers, memberFromNodeMeta(node.delegate.meta()))
+	return node.config.placement.Place(key, members, replicas), nil
+}
+
+// SetTags replaces the node tags and gossips them to the peers.
+// The peers get notified with a NodeUpdated event. When the node is started, the call waits for
+// the update to be broadcast until the context deadline, defaulting to the shutdown timeout.
+func (node *Node) SetTags(ctx context.Context, tags map[string]string) error {
 	node.mu.Lock()
-	address := node.discoveryAddress
-	node.mu.Unlock()
-	return address
+	defer node.mu.Unlock()
+
+	if err := node.delegate.setTags(tags); err != nil {
+		return err
+	}
+
+	// the tags will be gossiped when the node starts
+	if !node.started.Load() {
+		return nil
+	}
+
+	timeout := node.config.shutdownTimeout
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+	}
+
+	if err := node.memberlist.UpdateNode(timeout); err != nil {
+		return fmt.Errorf("failed to gossip the node tags: %w", err)
+	}
+	return nil
+}
+
+// Peers returns the list of peers.
+// When filters are given, only the peers matching all of them are returned, for instance:
+//
+//	peers, err := node.Peers(gokv.HasTag("zone", "eu-west-1a"))
+func (node *Node) Peers(filters ...PeerFilter) ([]*Member, error) {
+	metas, err := node.peersMeta()
+	if err != nil {
+		return nil, err
+	}
+
+	members := make([]*Member, 0, len(metas))
+	for _, meta := range metas {
+		if member := memberFromNodeMeta(meta); matches(member, filters) {
+			members = append(members, member)
+		}
+	}
+	return members, nil
+}
+
+// matches returns true when the member matches all the given filters
+func matches(member *Member, filters []PeerFilter) bool {
+	for _, filter := range filters {
+		if !filter(member) {
+			return false
+		}
+	}
+	return true
+}
+
+// ClusterInfo returns the cluster members as seen by the node
+// nolint
+func (node *Node) ClusterInfo(ctx context.Context, request *connect.Request[internalpb.ClusterInfoRequest]) (*connect.Response[internalpb.ClusterInfoResponse], error) {
+	if !node.started.Load() {
+		return nil, connect.NewError(connect.CodeFailedPrecondition, ErrNodeNotStarted)
+	}
+
+	response, err := node.clusterInfo()
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInternal, err)
+	}
+	return connect.NewResponse(response), nil
 }
 
-// Peers returns the list of peers
-func (node *Node) Peers() ([]*Member, error) {
+// peersMeta returns the metadata of the node peers
+func (node *Node) peersMeta() ([]*internalpb.NodeMeta, error) {
 	node.mu.Lock()
 	mnodes := node.memberlist.Members()
 	node.mu.Unlock()
-	members := make([]*Member, 0, len(mnodes))
+	metas := make([]*internalpb.NodeMeta, 0, len(mnodes))
 	for _, mnode := range mnodes {
-		member, err := memberFromMeta(mnode.Meta)
-		if err != nil {
+		meta := new(internalpb.NodeMeta)
+		if err := proto.Unmarshal(mnode.Meta, meta); err != nil {
 			return nil, err
 		}
-		if member != nil && member.DiscoveryAddress() != node.HostPort() {
-			members = append(members, member)
+		if lib.HostPort(meta.GetHost(), int(meta.GetDiscoveryPort())) != node.HostPort() {
+			metas = append(metas, meta)
 		}
 	}
-	return members, nil
+	return metas, nil
+}
+
+// clusterInfo builds the cluster information as seen by the node
+func (node *Node) clusterInfo() (*internalpb.ClusterInfoResponse, error) {
+	metas, err := node.peersMeta()
+	if err != nil {
+		return nil, err
+	}
+
+	peers := make([]*internalpb.PeerInfo, 0, len(metas))
+	for _, meta := range metas {
+		peer := &internalpb.PeerInfo{Meta: meta}
+		nodeID := lib.HostPort(meta.GetHost(), int(meta.GetDiscoveryPort()))
+		if lastSync := node.delegate.lastSyncTime(nodeID); !lastSync.IsZero() {
+			peer.LastSyncTime = timestamppb.New(lastSync)
+		}
+		peers = append(peers, peer)
+	}
+
+	return &internalpb.ClusterInfoResponse{
+		Self:  node.delegate.meta(),
+		Peers: peers,
+		Ready: node.ready(),
+	}, nil
+}
+
+// ready returns true when the node has joined the cluster and has completed its first push/pull
+// A node that is the only cluster member is ready as soon as it has joined the cluster
+func (node *Node) ready() bool {
+	if !node.started.Load() {
+		return false
+	}
+	return node.delegate.synced() || node.numMembers() <= 1
 }
 
 // serve start the underlying http server
@@ -350,13 +727,37 @@ func (node *Node) serve(ctx context.Context) error {
 	node.config.WithHost(host)
 	node.config.WithPort(uint16(port))
 
+	interceptors := node.config.interceptors
+	// the cluster nodes and clients are trusted hence the incoming trace context is used as the handler span parent
+	interceptor, err := newTelemetryInterceptor(node.config.meterProvider, node.config.tracerProvider, otelconnect.WithTrustRemote())
+	if err != nil {
+		return fmt.Errorf("failed to create the telemetry interceptor: %w", err)
+	}
+
+	if interceptor != nil {
+		interceptors = append([]connect.Interceptor{interceptor}, interceptors...)
+	}
+
 	// hook the node as the KV service handler
-	// TODO: add metric options to the handler
-	pattern, handler := internalpbconnect.NewKVServiceHandler(node)
+	pattern, handler := internalpbconnect.NewKVServiceHandler(node,
+		connect.WithInterceptors(interceptors...))
 
 	mux := nethttp.NewServeMux()
-	mux.Handle(pattern, handler)
-	server := http.NewServer(ctx, node.config.host, int(node.config.port), mux)
+	mux.Handle(pattern, withoutStreamDeadlines(handler))
+	mux.HandleFunc("/healthz", node.healthz)
+	mux.HandleFunc("/readyz", node.readyz)
+	mux.HandleFunc("/cluster", node.cluster)
+	if node.config.metricsHandler != nil {
+		mux.Handle("/metrics", node.config.metricsHandler)
+	}
+	server := http.NewServer(ctx, node.config.host, int(node.config.port), mux, &http.ServerConfig{
+		ReadTimeout:          node.config.serverReadTimeout,
+		ReadHeaderTimeout:    node.config.serverReadHeaderTimeout,
+		WriteTimeout:         node.config.serverWriteTimeout,
+		IdleTimeout:          node.config.serverIdleTimeout,
+		MaxRequestBodySize:   node.config.maxRequestBodySize,
+		MaxConcurrentStreams: node.config.maxConcurrentStreams,
+	})
 
 	node.httpServer = server
 
@@ -371,7 +772,46 @@ func (node *Node) serve(ctx context.Context) error {
 	return nil
 }
 
+// annotate sets the given key and the serving node on the span of the handled request
+func (node *Node) annotate(ctx context.Context, key string) {
+	trace.SpanFromContext(ctx).SetAttributes(
+		keyAttribute.String(key),
+		nodeAttribute.String(node.discoveryAddress))
+}
+
+// registerMetrics registers the node observable metrics when a meter provider is set
+func (node *Node) registerMetrics() error {
+	if node.config.meterProvider == nil {
+		return nil
+	}
+
+	registration, err := registerNodeMetrics(node.config.meterProvider, node)
+	if err != nil {
+		return fmt.Errorf("failed to register the node metrics: %w", err)
+	}
+	node.metricsRegistration = registration
+	return nil
+}
+
+// unregisterMetrics unregisters the node observable metrics
+func (node *Node) unregisterMetrics() error {
+	if node.metricsRegistration == nil {
+		return nil
+	}
+	return node.metricsRegistration.Unregister()
+}
+
+// numMembers returns the number of cluster members known by the node
+func (node *Node) numMembers() int {
+	if !node.started.Load() {
+		return 0
+	}
+	return node.memberlist.NumMembers()
+}
+
 // join attempts to join an existing cluster if node peers is provided
+// The peers discovery and the join are retried with an exponential backoff and jitter
+// starting at the join retry interval and bounded by the max join attempts.
 func (node *Node) join() error {
 	mlist, err := memberlist.Create(node.memberConfig)
 	if err != nil {
@@ -379,47 +819,147 @@ func (node *Node) join() error {
 		return err
 	}
 
-	// TODO: use a retry mechanism here
-	peers, err := node.config.provider.DiscoverPeers()
-	if err != nil {
-		node.config.logger.Error(fmt.Errorf("failed to discover peers: %w", err))
-		return err
-	}
-
 	// set the mlist
 	node.memberlist = mlist
-	if len(peers) > 0 {
-		if _, err := node.memberlist.Join(peers); err != nil {
-			node.config.logger.Error(fmt.Errorf("failed to join cluster: %w", err))
+
+	retrier := retry.NewRetrier(node.config.maxJoinAttempts,
+		node.config.joinRetryInterval,
+		time.Duration(node.config.maxJoinAttempts)*node.config.joinRetryInterval)
+
+	if err := retrier.Run(func() error {
+		_, err := node.discoverAndJoin()
+		return err
+	}); err != nil {
+		if !node.config.singleNodeStart {
+			node.config.logger.Error(err)
+			_ = node.memberlist.Shutdown()
 			return err
 		}
-		node.config.logger.Infof("%s successfully joined cluster: [%s]", node.discoveryAddress, strings.Join(peers, ","))
+		node.config.logger.Warnf("%s starting as a single-node cluster: %v", node.discoveryAddress, err)
 	}
+
+	// keep discovering the peers in the background
+	go node.rediscoverPeers()
 	return nil
 }
 
+// discoverAndJoin discovers the cluster peers and joins the ones that are not yet members of the cluster.
+// It returns the number of peers joined
+func (node *Node) discoverAndJoin() (int, error) {
+	discovered, err := node.config.provider.DiscoverPeers()
+	if err != nil {
+		return 0, fmt.Errorf("failed to discover peers: %w", err)
+	}
+
+	// the discovery provider can return the node own address and the known members
+	members := map[string]struct{}{node.discoveryAddress: {}}
+	for _, member := range node.memberlist.Members() {
+		members[member.Address()] = struct{}{}
+	}
+
+	peers := make([]string, 0, len(discovered))
+	for _, peer := range discovered {
+		if !node.knownPeer(peer, members) {
+			peers = append(peers, peer)
+		}
+	}
+
+	if len(peers) == 0 {
+		return 0, nil
+	}
+
+	joined, err := node.memberlist.Join(peers)
+	if err != nil {
+		return joined, fmt.Errorf("failed to join cluster: %w", err)
+	}
+
+	node.config.logger.Infof("%s successfully joined cluster: [%s]", node.discoveryAddress, strings.Join(peers, ","))
+	return joined, nil
+}
+
+// knownPeer tells whether a discovered peer address is the node own address or a cluster member address.
+// The members are known by their IP address, hence a peer discovered by its hostname is resolved first
+func (node *Node) knownPeer(peer string, members map[string]struct{}) bool {
+	if _, ok := members[peer]; ok {
+		return true
+	}
+
+	host, port, err := net.SplitHostPort(peer)
+	if err != nil || net.ParseIP(host) != nil {
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), node.memberConfig.TCPTimeout)
+	defer cancel()
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return false
+	}
+
+	for _, addr := range addrs {
+		if _, ok := members[net.JoinHostPort(addr.IP.String(), port)]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// rediscoverPeers periodically discovers the cluster peers and joins the ones that are not yet members
+// of the cluster. This heals network partitions and lets the cluster converge when the peers addresses change.
+func (node *Node) rediscoverPeers() {
+	for {
+		interval := node.rediscoveryInterval()
+		if interval <= 0 {
+			return
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-timer.C:
+			if _, err := node.discoverAndJoin(); err != nil {
+				node.config.logger.Debugf("%s failed to rediscover peers: %v", node.discoveryAddress, err)
+			}
+		case <-node.stopRediscovery:
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// rediscoveryInterval returns the delay before the next peers discovery.
+// A node started as a single-node cluster discovers its peers at every join retry interval until it joins them
+func (node *Node) rediscoveryInterval() time.Duration {
+	if node.config.singleNodeStart && node.memberlist.NumMembers() <= 1 {
+		return node.config.joinRetryInterval
+	}
+	return node.config.rediscoveryInterval
+}
+
 // eventsListener listens to cluster events to handle them
 func (node *Node) eventsListener(eventsCh chan memberlist.NodeEvent) {
 	for {
 		select {
 		case event := <-eventsCh:
 			// skip this node
-			if event.Node == nil {
-				addr := net.JoinHostPort(event.Node.Addr.String(), strconv.Itoa(int(event.Node.Port)))
-				if addr == node.HostPort() {
-					continue
-				}
+			if event.Node == nil || event.Node.Name == node.memberConfig.Name {
+				continue
 			}
 
 			var eventType EventType
 			switch event.Event {
 			case memberlist.NodeJoin:
+				// forget any stale leave announcement of a rejoining node
+				node.delegate.left(event.Node.Name)
 				eventType = NodeJoined
 			case memberlist.NodeLeave:
-				eventType = NodeLeft
+				// a node leaving without announcing it has been declared dead
+				eventType = NodeDead
+				if node.delegate.left(event.Node.Name) {
+					eventType = NodeLeft
+				}
 			case memberlist.NodeUpdate:
-				// TODO: maybe handle this
-				continue
+				eventType = NodeUpdated
 			}
 
 			// parse the node meta information, log an eventual error during parsing and skip the event
@@ -429,18 +969,78 @@ func (node *Node) eventsListener(eventsCh chan memberlist.NodeEvent) {
 				continue
 			}
 
-			// send the event to the event channels
-			node.eventsLock.Lock()
-			node.eventsChan <- &Event{
+			node.publish(&Event{
 				Member: member,
 				Time:   time.Now().UTC(),
 				Type:   eventType,
-			}
-			node.eventsLock.Unlock()
+			})
 		case <-node.stopEventsListener:
 			// finish listening to cluster events
-			close(node.eventsChan)
+			node.closeSubscriptions()
 			return
 		}
 	}
 }
+
+// announceLeave notifies the peers concurrently that the node is gracefully leaving the cluster.
+// It waits for the notifications until the shutdown timeout
+func (node *Node) announceLeave(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, node.config.shutdownTimeout)
+	defer cancel()
+
+	message := append([]byte{leaveMessage}, node.memberConfig.Name...)
+	wg := new(sync.WaitGroup)
+	for _, member := range node.memberlist.Members() {
+		if member.Name == node.memberConfig.Name {
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := node.memberlist.SendReliable(member, message); err != nil {
+				node.config.logger.Debugf("%s failed to announce its leave to %s: %v", node.discoveryAddress, member.Name, err)
+			}
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		node.config.logger.Debugf("%s failed to announce its leave to all its peers: %v", node.discoveryAddress, ctx.Err())
+	}
+}
+
+// publishKeyEvent publishes a key event of the node local state
+func (node *Node) publishKeyEvent(eventType EventType, key string) {
+	node.publish(&Event{
+		Member: node.delegate.member.Load(),
+		Time:   time.Now().UTC(),
+		Type:   eventType,
+		Key:    key,
+	})
+}
+
+// publish delivers the event to all the subscriptions.
+// A subscription cancelled meanwhile is closed and ignores the event
+func (node *Node) publish(event *Event) {
+	for _, subscription := range *node.subscriptions.Load() {
+		subscription.publish(event)
+	}
+}
+
+// closeSubscriptions closes all the subscriptions
+func (node *Node) closeSubscriptions() {
+	node.eventsLock.Lock()
+	node.eventsClosed = true
+	for _, subscription := range *node.subscriptions.Swap(new([]*Subscription)) {
+		subscription.close()
+	}
+	node.eventsLock.Unlock()
+}
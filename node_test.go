@@ -27,20 +27,34 @@ package gokv
 import (
 	"context"
 	"encoding/base64"
+	"errors"
 	"fmt"
-	"reflect"
+	"net"
+	nethttp "net/http"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
+	"connectrpc.com/connect"
 	natsserver "github.com/nats-io/nats-server/v2/server"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/travisjeffery/go-dynaport"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.uber.org/atomic"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
 	"github.com/tochemey/gokv/discovery"
 	"github.com/tochemey/gokv/discovery/nats"
+	"github.com/tochemey/gokv/internal/internalpb"
 	"github.com/tochemey/gokv/internal/lib"
 	"github.com/tochemey/gokv/log"
+	mocks "github.com/tochemey/gokv/mocks/discovery"
 )
 
 func TestNodes(t *testing.T) {
@@ -63,8 +77,8 @@ func TestNodes(t *testing.T) {
 	// let us distribute a key in the cluster
 	key := "some-key"
 	value := []byte("some-value")
-	entry := &Entry{key, value}
-	err := node2.Client().Put(ctx, entry, NoExpiration)
+	entry := &Entry{Key: key, Value: value}
+	err := node2.Client().Put(ctx, entry)
 	require.NoError(t, err)
 
 	// wait for the key to be distributed in the cluster
@@ -74,18 +88,22 @@ func TestNodes(t *testing.T) {
 	exists, err := node1.Client().Exists(ctx, key)
 	require.NoError(t, err)
 	require.True(t, exists)
-	actual, err := node1.Client().Get(ctx, key)
+	actual, _, err := node1.Client().GetWithMetadata(ctx, key)
 	require.NoError(t, err)
 	require.NotNil(t, actual)
-	require.True(t, reflect.DeepEqual(entry, actual))
+	require.Equal(t, entry.Key, actual.Key)
+	require.Equal(t, entry.Value, actual.Value)
+	require.Equal(t, node2.delegate.self, actual.Origin)
 
 	exists, err = node3.Client().Exists(ctx, key)
 	require.NoError(t, err)
 	require.True(t, exists)
-	actual, err = node3.Client().Get(ctx, key)
+	actual, _, err = node3.Client().GetWithMetadata(ctx, key)
 	require.NoError(t, err)
 	require.NotNil(t, actual)
-	require.True(t, reflect.DeepEqual(entry, actual))
+	require.Equal(t, entry.Key, actual.Key)
+	require.Equal(t, entry.Value, actual.Value)
+	require.Equal(t, node2.delegate.self, actual.Origin)
 
 	// let us remove the key
 	require.NoError(t, node2.Client().Delete(ctx, key))
@@ -110,6 +128,114 @@ func TestNodes(t *testing.T) {
 	})
 }
 
+func TestClusterOwnership(t *testing.T) {
+	ctx := context.Background()
+	// start the NATS server
+	srv := startNatsServer(t)
+
+	node1, sd1 := startNode(t, srv.Addr().String())
+	node2, sd2 := startNode(t, srv.Addr().String())
+
+	// the key is written on node1 and updated on node2
+	require.NoError(t, node1.Client().PutString(ctx, "key", "value", NoExpiration))
+	lib.Pause(time.Second)
+	require.NoError(t, node2.Client().PutString(ctx, "key", "updated", NoExpiration))
+	lib.Pause(time.Second)
+
+	for _, node := range []*Node{node1, node2} {
+		actual, err := node.Client().GetString(ctx, "key")
+		require.NoError(t, err)
+		assert.Equal(t, "updated", actual)
+
+		entries, err := node.Client().List(ctx)
+		require.NoError(t, err)
+		assert.Len(t, entries, 1)
+	}
+
+	// the key is deleted on node1 which does not hold it anymore
+	require.NoError(t, node1.Client().Delete(ctx, "key"))
+	lib.Pause(time.Second)
+
+	for _, node := range []*Node{node1, node2} {
+		exists, err := node.Client().Exists(ctx, "key")
+		require.NoError(t, err)
+		assert.False(t, exists)
+	}
+
+	t.Cleanup(func() {
+		assert.NoError(t, node1.Stop(ctx))
+		assert.NoError(t, node2.Stop(ctx))
+		assert.NoError(t, sd1.Close())
+		assert.NoError(t, sd2.Close())
+		srv.Shutdown()
+	})
+}
+
+func TestDeleteBeforeMerge(t *testing.T) {
+	ctx := context.Background()
+	// start the NATS server
+	srv := startNatsServer(t)
+
+	syncInterval := func(config *Config) { config.syncInterval = 3 * time.Second }
+	node1, sd1 := startNode(t, srv.Addr().String(), syncInterval)
+	node2, sd2 := startNode(t, srv.Addr().String(), syncInterval)
+
+	// the key is written on node1 and deleted on node2 before node2 merges the node1 state
+	require.NoError(t, node1.Client().PutString(ctx, "key", "value", NoExpiration))
+	exists, err := node2.Client().Exists(ctx, "key")
+	require.NoError(t, err)
+	require.False(t, exists)
+	require.NoError(t, node2.Client().Delete(ctx, "key"))
+	deleted := time.Now().UTC()
+
+	// wait for both nodes to merge each other state
+	require.Eventually(t, func() bool {
+		return node1.delegate.lastSyncTime(node2.delegate.self).After(deleted) &&
+			node2.delegate.lastSyncTime(node1.delegate.self).After(deleted)
+	}, 10*time.Second, 100*time.Millisecond)
+
+	for _, node := range []*Node{node1, node2} {
+		exists, err := node.Client().Exists(ctx, "key")
+		require.NoError(t, err)
+		assert.False(t, exists)
+	}
+
+	t.Cleanup(func() {
+		assert.NoError(t, node1.Stop(ctx))
+		assert.NoError(t, node2.Stop(ctx))
+		assert.NoError(t, sd1.Close())
+		assert.NoError(t, sd2.Close())
+		srv.Shutdown()
+	})
+}
+
+func TestTombstonesPurge(t *testing.T) {
+	ctx := context.Background()
+	// start the NATS server
+	srv := startNatsServer(t)
+
+	// the cleaner job is disabled
+	node, sd := startNode(t, srv.Addr().String(), func(config *Config) {
+		config.tombstoneRetention = 100 * time.Millisecond
+	})
+
+	require.NoError(t, node.Client().PutString(ctx, "key", "value", NoExpiration))
+	require.NoError(t, node.Client().Delete(ctx, "key"))
+	_, exists := node.delegate.local.peek("key")
+	require.True(t, exists)
+
+	require.Eventually(t, func() bool {
+		_, exists := node.delegate.local.peek("key")
+		return !exists
+	}, 2*time.Second, 50*time.Millisecond)
+
+	t.Cleanup(func() {
+		assert.NoError(t, node.Stop(ctx))
+		assert.NoError(t, sd.Close())
+		srv.Shutdown()
+	})
+}
+
 func TestClusterEvents(t *testing.T) {
 	ctx := context.Background()
 
@@ -192,6 +318,798 @@ L2:
 	})
 }
 
+func TestKeyEvents(t *testing.T) {
+	ctx := context.Background()
+	// start the NATS server
+	srv := startNatsServer(t)
+
+	// create a cluster node
+	node, sd := startNode(t, srv.Addr().String())
+	require.NotNil(t, node)
+
+	// create two independent subscribers
+	keys := node.Subscribe(WithEventTypes(KeyAdded, KeyUpdated, KeyDeleted))
+	all := node.Subscribe(WithEventsBufferSize(1), WithDropPolicy(DropNewest))
+
+	client := node.Client()
+	require.NoError(t, client.PutString(ctx, "key", "value", NoExpiration))
+	require.NoError(t, client.PutString(ctx, "key", "value2", NoExpiration))
+	require.NoError(t, client.Delete(ctx, "key"))
+	// deleting a missing key does not emit any event
+	require.NoError(t, client.Delete(ctx, "key"))
+
+	var types []EventType
+	for i := 0; i < 3; i++ {
+		select {
+		case event := <-keys.Events():
+			assert.Equal(t, "key", event.Key)
+			assert.Equal(t, node.HostPort(), event.Member.DiscoveryAddress())
+			types = append(types, event.Type)
+		case <-time.After(time.Second):
+			t.Fatal("key event not received")
+		}
+	}
+	assert.Equal(t, []EventType{KeyAdded, KeyUpdated, KeyDeleted}, types)
+
+	// the slow subscriber does not block the others
+	assert.Len(t, all.Events(), 1)
+	assert.EqualValues(t, 2, all.Dropped())
+
+	// the buffered events are still delivered before the channel closes
+	node.Unsubscribe(all)
+	var remaining []*Event
+	for event := range all.Events() {
+		remaining = append(remaining, event)
+	}
+	require.Len(t, remaining, 1)
+	assert.Equal(t, KeyAdded, remaining[0].Type)
+
+	require.NoError(t, node.Stop(ctx))
+	// the subscriptions are closed when the node stops
+	require.Eventually(t, func() bool {
+		_, ok := <-keys.Events()
+		return !ok
+	}, time.Second, 10*time.Millisecond)
+
+	t.Cleanup(func() {
+		assert.NoError(t, sd.Close())
+		srv.Shutdown()
+	})
+}
+
+func TestNodeDeadEvent(t *testing.T) {
+	ctx := context.Background()
+	// start the NATS server
+	srv := startNatsServer(t)
+
+	// create a cluster node1
+	node1, sd1 := startNode(t, srv.Addr().String())
+	require.NotNil(t, node1)
+	subscription := node1.Subscribe(WithEventTypes(NodeLeft, NodeDead))
+
+	// create a cluster node2
+	node2, sd2 := startNode(t, srv.Addr().String())
+	require.NotNil(t, node2)
+
+	// node2 fails without leaving the cluster
+	require.NoError(t, node2.memberlist.Shutdown())
+
+	select {
+	case event := <-subscription.Events():
+		assert.Equal(t, NodeDead, event.Type)
+		assert.Equal(t, node2.HostPort(), event.Member.DiscoveryAddress())
+	case <-time.After(20 * time.Second):
+		t.Fatal("node dead event not received")
+	}
+
+	t.Cleanup(func() {
+		assert.NoError(t, node1.Stop(ctx))
+		assert.NoError(t, node2.httpServer.Shutdown(ctx))
+		assert.NoError(t, sd1.Close())
+		assert.NoError(t, sd2.Close())
+		srv.Shutdown()
+	})
+}
+
+func TestTags(t *testing.T) {
+	ctx := context.Background()
+	// start the NATS server
+	srv := startNatsServer(t)
+
+	// create a cluster node1
+	node1, sd1 := startNode(t, srv.Addr().String(), func(config *Config) {
+		config.WithTags(map[string]string{"zone": "a"})
+	})
+	require.NotNil(t, node1)
+	subscription := node1.Subscribe(WithEventTypes(NodeUpdated))
+
+	// create a cluster node2
+	node2, sd2 := startNode(t, srv.Addr().String(), func(config *Config) {
+		config.WithTags(map[string]string{"zone": "b", "role": "cache"})
+	})
+	require.NotNil(t, node2)
+
+	peers, err := node1.Peers()
+	require.NoError(t, err)
+	require.Len(t, peers, 1)
+	assert.Equal(t, map[string]string{"zone": "b", "role": "cache"}, peers[0].Tags)
+
+	peers, err = node1.Peers(HasTag("zone", "b"), HasTag("role", "cache"))
+	require.NoError(t, err)
+	require.Len(t, peers, 1)
+
+	peers, err = node1.Peers(HasTag("zone", "a"))
+	require.NoError(t, err)
+	require.Empty(t, peers)
+
+	// move node2 to node1 zone
+	require.NoError(t, node2.SetTags(ctx, map[string]string{"zone": "a"}))
+
+	select {
+	case event := <-subscription.Events():
+		assert.Equal(t, node2.HostPort(), event.Member.DiscoveryAddress())
+		assert.Equal(t, map[string]string{"zone": "a"}, event.Member.Tags)
+	case <-time.After(5 * time.Second):
+		t.Fatal("node updated event not received")
+	}
+
+	peers, err = node1.Peers(HasTag("zone", "a"))
+	require.NoError(t, err)
+	require.Len(t, peers, 1)
+	assert.Equal(t, node2.HostPort(), peers[0].DiscoveryAddress())
+
+	// the node metadata is limited in size
+	tags := map[string]string{"blob": strings.Repeat("x", 1024)}
+	assert.ErrorIs(t, node2.SetTags(ctx, tags), ErrNodeMetaTooLarge)
+
+	t.Cleanup(func() {
+		assert.NoError(t, node1.Stop(ctx))
+		assert.NoError(t, node2.Stop(ctx))
+		assert.NoError(t, sd1.Close())
+		assert.NoError(t, sd2.Close())
+		srv.Shutdown()
+	})
+}
+
+func TestReplicas(t *testing.T) {
+	ctx := context.Background()
+	// start the NATS server
+	srv := startNatsServer(t)
+
+	// create three nodes in three zones
+	node1, sd1 := startNode(t, srv.Addr().String(), func(config *Config) { config.WithZone("zone-1") })
+	node2, sd2 := startNode(t, srv.Addr().String(), func(config *Config) { config.WithZone("zone-2") })
+	node3, sd3 := startNode(t, srv.Addr().String(), func(config *Config) { config.WithZone("zone-3") })
+
+	peers, err := node1.Peers()
+	require.NoError(t, err)
+	require.Len(t, peers, 2)
+	for _, peer := range peers {
+		assert.NotEmpty(t, peer.Zone)
+	}
+
+	replicas, err := node1.Replicas("key", 2)
+	require.NoError(t, err)
+	require.Len(t, replicas, 2)
+	assert.NotEqual(t, replicas[0].Zone, replicas[1].Zone)
+
+	// every node agrees on the placement
+	others, err := node2.Replicas("key", 2)
+	require.NoError(t, err)
+	require.Len(t, others, 2)
+	assert.Equal(t, replicas[0].Name, others[0].Name)
+	assert.Equal(t, replicas[1].Name, others[1].Name)
+
+	t.Cleanup(func() {
+		assert.NoError(t, node1.Stop(ctx))
+		assert.NoError(t, node2.Stop(ctx))
+		assert.NoError(t, node3.Stop(ctx))
+		assert.NoError(t, sd1.Close())
+		assert.NoError(t, sd2.Close())
+		assert.NoError(t, sd3.Close())
+		srv.Shutdown()
+	})
+}
+
+func TestReadTimeout(t *testing.T) {
+	ctx := context.Background()
+	// start the NATS server
+	srv := startNatsServer(t)
+
+	node, sd := startNode(t, srv.Addr().String(), func(config *Config) {
+		config.WithReadTimeout(100 * time.Millisecond)
+	})
+	client := node.Client()
+	require.NoError(t, client.PutString(ctx, "key", "value", NoExpiration))
+
+	// block the node reads
+	shard := node.delegate.local.shard("key")
+	shard.Lock()
+	_, err := client.Get(ctx, "key")
+	require.Error(t, err)
+	assert.Equal(t, connect.CodeDeadlineExceeded, connect.CodeOf(err))
+	_, err = client.List(ctx)
+	require.Error(t, err)
+	assert.Equal(t, connect.CodeDeadlineExceeded, connect.CodeOf(err))
+	shard.Unlock()
+
+	value, err := client.GetString(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, "value", value)
+
+	t.Cleanup(func() {
+		assert.NoError(t, node.Stop(ctx))
+		assert.NoError(t, sd.Close())
+		srv.Shutdown()
+	})
+}
+
+func TestLimits(t *testing.T) {
+	ctx := context.Background()
+	// start the NATS server
+	srv := startNatsServer(t)
+
+	node, sd := startNode(t, srv.Addr().String(), func(config *Config) {
+		config.WithMaxKeySize(8).WithMaxValueSize(8).WithMaxEntries(2).WithMaxTotalSize(20)
+	})
+	client := node.Client()
+
+	err := client.PutString(ctx, "very-long-key", "value", NoExpiration)
+	assert.ErrorIs(t, err, ErrKeyTooLarge)
+	err = client.PutString(ctx, "key", "very-long-value", NoExpiration)
+	assert.ErrorIs(t, err, ErrValueTooLarge)
+	err = client.Delete(ctx, "very-long-key")
+	assert.ErrorIs(t, err, ErrKeyTooLarge)
+
+	require.NoError(t, client.PutString(ctx, "key1", "value1", NoExpiration))
+	// the total size includes the keys and the values
+	err = client.PutString(ctx, "key2", "value2-x", NoExpiration)
+	assert.ErrorIs(t, err, ErrStoreFull)
+	require.NoError(t, client.PutString(ctx, "key2", "value2", NoExpiration))
+
+	err = client.PutString(ctx, "key3", "v", NoExpiration)
+	assert.ErrorIs(t, err, ErrTooManyEntries)
+
+	// replacing an existing key does not count against the entries limit
+	require.NoError(t, client.PutString(ctx, "key1", "value", NoExpiration))
+	// the rejected writes are not stored
+	exists, err := client.Exists(ctx, "key3")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	// the typed errors are mapped from the error details and not from the error messages
+	err = limitError(connect.NewError(connect.CodeInvalidArgument, errors.New(ErrKeyTooLarge.Error())))
+	assert.NotErrorIs(t, err, ErrKeyTooLarge)
+	assert.Equal(t, connect.CodeInvalidArgument, connect.CodeOf(err))
+	err = limitError(limitViolation(connect.CodeResourceExhausted, errors.New("some error")))
+	assert.Empty(t, err.(*connect.Error).Details())
+
+	t.Cleanup(func() {
+		assert.NoError(t, node.Stop(ctx))
+		assert.NoError(t, sd.Close())
+		srv.Shutdown()
+	})
+}
+
+func TestStateTransfer(t *testing.T) {
+	ctx := context.Background()
+	// start the NATS server
+	srv := startNatsServer(t)
+
+	// the node local state is transferred in several chunks
+	withSmallChunks := func(config *Config) { config.WithStateChunkSize(64) }
+	node1, sd1 := startNode(t, srv.Addr().String(), withSmallChunks)
+	for i := 0; i < 100; i++ {
+		require.NoError(t, node1.Client().PutString(ctx, fmt.Sprintf("key-%d", i), "value", NoExpiration))
+	}
+
+	// the joining node bootstraps from the transferred state
+	node2, sd2 := startNode(t, srv.Addr().String(), withSmallChunks)
+	entries, err := node2.Client().List(ctx)
+	require.NoError(t, err)
+	assert.Len(t, entries, 100)
+	assert.True(t, node2.ready())
+
+	t.Cleanup(func() {
+		assert.NoError(t, node1.Stop(ctx))
+		assert.NoError(t, node2.Stop(ctx))
+		assert.NoError(t, sd1.Close())
+		assert.NoError(t, sd2.Close())
+		srv.Shutdown()
+	})
+}
+
+func TestDrain(t *testing.T) {
+	ctx := context.Background()
+	// start the NATS server
+	srv := startNatsServer(t)
+
+	node1, sd1 := startNode(t, srv.Addr().String())
+	client := node1.Client()
+	require.NoError(t, client.PutString(ctx, "key", "value", NoExpiration))
+
+	// a node without peers cannot hand off its entries and keeps running
+	require.ErrorIs(t, node1.Drain(ctx), ErrNoPeers)
+	require.NoError(t, client.PutString(ctx, "key", "value", NoExpiration))
+
+	node2, sd2 := startNode(t, srv.Addr().String())
+	node3, sd3 := startNode(t, srv.Addr().String())
+	for i := 0; i < 20; i++ {
+		require.NoError(t, client.PutString(ctx, fmt.Sprintf("key-%d", i), "value", NoExpiration))
+	}
+	// the removed entry is handed off as well
+	require.NoError(t, client.Delete(ctx, "key-0"))
+
+	// the entries are only accepted from the cluster members
+	request := &internalpb.HandoffRequest{
+		NodeId:  "unknown",
+		Entries: []*internalpb.Entry{{Key: "intruder", Value: []byte("value"), LastUpdatedTime: timestamppb.Now()}},
+	}
+	_, err := node2.Client().kvService.Handoff(ctx, connect.NewRequest(request))
+	require.Equal(t, connect.CodePermissionDenied, connect.CodeOf(err))
+	request.NodeId = node2.memberConfig.Name
+	_, err = node2.Client().kvService.Handoff(ctx, connect.NewRequest(request))
+	require.Equal(t, connect.CodePermissionDenied, connect.CodeOf(err))
+
+	subscriptions := []*Subscription{node2.Subscribe(), node3.Subscribe()}
+	require.NoError(t, node1.Drain(ctx))
+	assert.False(t, node1.started.Load())
+
+	// every entry is now held by one of the remaining nodes
+	owned := int(node2.delegate.local.len() + node3.delegate.local.len())
+	assert.Equal(t, 20, owned)
+
+	actual, err := node2.Client().GetString(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, "value", actual)
+
+	exists, err := node2.Client().Exists(ctx, "key-0")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	// the handed off removed entry is not notified as a write
+	for _, subscription := range subscriptions {
+	events:
+		for {
+			select {
+			case event := <-subscription.Events():
+				if event.Key == "key-0" {
+					assert.Equal(t, KeyDeleted, event.Type)
+				}
+			default:
+				break events
+			}
+		}
+	}
+
+	t.Cleanup(func() {
+		assert.NoError(t, node2.Stop(ctx))
+		assert.NoError(t, node3.Stop(ctx))
+		assert.NoError(t, sd1.Close())
+		assert.NoError(t, sd2.Close())
+		assert.NoError(t, sd3.Close())
+		srv.Shutdown()
+	})
+}
+
+func TestEvictionPolicy(t *testing.T) {
+	ctx := context.Background()
+	// start the NATS server
+	srv := startNatsServer(t)
+
+	node1, sd1 := startNode(t, srv.Addr().String(), func(config *Config) {
+		config.WithMaxEntries(2).WithEvictionPolicy(LRU)
+	})
+	node2, sd2 := startNode(t, srv.Addr().String())
+	evictions := node1.Subscribe(WithEventTypes(KeyEvicted))
+
+	client := node1.Client()
+	require.NoError(t, client.PutString(ctx, "key1", "value", NoExpiration))
+	require.NoError(t, client.PutString(ctx, "key2", "value", NoExpiration))
+	// key1 becomes the most recently used
+	_, err := client.Get(ctx, "key1")
+	require.NoError(t, err)
+	require.NoError(t, client.PutString(ctx, "key3", "value", NoExpiration))
+
+	select {
+	case event := <-evictions.Events():
+		assert.Equal(t, "key2", event.Key)
+	case <-time.After(time.Second):
+		t.Fatal("eviction event not received")
+	}
+
+	// the eviction is replicated in the cluster
+	peer := node2.Client()
+	require.Eventually(t, func() bool {
+		evicted, err := peer.Exists(ctx, "key2")
+		if err != nil || evicted {
+			return false
+		}
+		kept, err := peer.Exists(ctx, "key3")
+		return err == nil && kept
+	}, 5*time.Second, 100*time.Millisecond)
+
+	t.Cleanup(func() {
+		assert.NoError(t, node1.Stop(ctx))
+		assert.NoError(t, node2.Stop(ctx))
+		assert.NoError(t, sd1.Close())
+		assert.NoError(t, sd2.Close())
+		srv.Shutdown()
+	})
+}
+
+func TestEvictionBeforeMerge(t *testing.T) {
+	ctx := context.Background()
+	// start the NATS server
+	srv := startNatsServer(t)
+
+	syncInterval := func(config *Config) { config.syncInterval = 3 * time.Second }
+	node1, sd1 := startNode(t, srv.Addr().String(), syncInterval, func(config *Config) {
+		config.WithMaxEntries(1).WithEvictionPolicy(LRU)
+	})
+	node2, sd2 := startNode(t, srv.Addr().String(), syncInterval)
+
+	// the key is written on node2, then updated and evicted on node1 before the nodes merge each other state
+	require.NoError(t, node2.Client().PutString(ctx, "key", "value", NoExpiration))
+	require.NoError(t, node1.Client().PutString(ctx, "key", "updated", NoExpiration))
+	require.NoError(t, node1.Client().PutString(ctx, "other", "value", NoExpiration))
+	evicted := time.Now().UTC()
+
+	// wait for both nodes to merge each other state
+	require.Eventually(t, func() bool {
+		return node1.delegate.lastSyncTime(node2.delegate.self).After(evicted) &&
+			node2.delegate.lastSyncTime(node1.delegate.self).After(evicted)
+	}, 10*time.Second, 100*time.Millisecond)
+
+	for _, node := range []*Node{node1, node2} {
+		exists, err := node.Client().Exists(ctx, "key")
+		require.NoError(t, err)
+		assert.False(t, exists)
+	}
+
+	t.Cleanup(func() {
+		assert.NoError(t, node1.Stop(ctx))
+		assert.NoError(t, node2.Stop(ctx))
+		assert.NoError(t, sd1.Close())
+		assert.NoError(t, sd2.Close())
+		srv.Shutdown()
+	})
+}
+
+func TestMetrics(t *testing.T) {
+	ctx := context.Background()
+	// start the NATS server
+	srv := startNatsServer(t)
+
+	// create the metrics reader
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	handler := nethttp.HandlerFunc(func(writer nethttp.ResponseWriter, _ *nethttp.Request) {
+		writer.WriteHeader(nethttp.StatusOK)
+	})
+
+	// create a cluster node1
+	node1, sd1 := startNode(t, srv.Addr().String(), func(config *Config) {
+		config.WithMetrics(provider).WithMetricsHandler(handler)
+	})
+	require.NotNil(t, node1)
+
+	// create a cluster node2
+	node2, sd2 := startNode(t, srv.Addr().String())
+	require.NotNil(t, node2)
+
+	// let us distribute a key in the cluster
+	require.NoError(t, node1.Client().PutString(ctx, "key", "value", NoExpiration))
+
+	// wait for the key to be distributed in the cluster
+	lib.Pause(time.Second)
+
+	var resourceMetrics metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(ctx, &resourceMetrics))
+
+	names := make(map[string]bool)
+	for _, scopeMetrics := range resourceMetrics.ScopeMetrics {
+		for _, metric := range scopeMetrics.Metrics {
+			names[metric.Name] = true
+		}
+	}
+
+	for _, name := range []string{
+		"gokv.local.entries",
+		"gokv.local.bytes",
+		"gokv.peers.entries",
+		"gokv.peers.bytes",
+		"gokv.members",
+		"gokv.pushpull.size",
+		"gokv.pushpull.duration",
+		"rpc.server.duration",
+		"rpc.client.duration",
+	} {
+		assert.Truef(t, names[name], "metric %s not found", name)
+	}
+
+	// let us hit the metrics endpoint
+	response, err := nethttp.Get(fmt.Sprintf("http://%s/metrics", lib.HostPort(node1.config.host, int(node1.config.port))))
+	require.NoError(t, err)
+	assert.Equal(t, nethttp.StatusOK, response.StatusCode)
+	require.NoError(t, response.Body.Close())
+
+	t.Cleanup(func() {
+		assert.NoError(t, node1.Stop(ctx))
+		assert.NoError(t, node2.Stop(ctx))
+		assert.NoError(t, sd1.Close())
+		assert.NoError(t, sd2.Close())
+		srv.Shutdown()
+	})
+}
+
+func TestTracing(t *testing.T) {
+	ctx := context.Background()
+	// start the NATS server
+	srv := startNatsServer(t)
+
+	// create the spans recorder
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	// create a cluster node1
+	node1, sd1 := startNode(t, srv.Addr().String(), func(config *Config) {
+		config.WithTracing(provider)
+	})
+	require.NotNil(t, node1)
+
+	// create a cluster node2
+	node2, sd2 := startNode(t, srv.Addr().String())
+	require.NotNil(t, node2)
+
+	// let us distribute a key in the cluster from node2
+	key := "key"
+	require.NoError(t, node2.Client().PutString(ctx, key, "value", NoExpiration))
+
+	// wait for the key to be distributed in the cluster
+	lib.Pause(time.Second)
+
+	// let us fetch the key from node1
+	client := NewClient(node1.config.host, int(node1.config.port), WithClientTracing(provider))
+	actual, err := client.GetString(ctx, key)
+	require.NoError(t, err)
+	require.Equal(t, "value", actual)
+
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+
+	require.Contains(t, spans, "Client.Get")
+	// the client always requests the entry metadata
+	require.Contains(t, spans, "delegate.GetWithMetadata")
+	require.Contains(t, spans, "delegate.MergeRemoteState")
+
+	// the delegate span belongs to the same trace as the client span
+	clientSpan := spans["Client.Get"]
+	getSpan := spans["delegate.GetWithMetadata"]
+	assert.Equal(t, clientSpan.SpanContext().TraceID(), getSpan.SpanContext().TraceID())
+
+	attributes := make(map[attribute.Key]attribute.Value)
+	for _, kv := range getSpan.Attributes() {
+		attributes[kv.Key] = kv.Value
+	}
+	assert.Equal(t, key, attributes[keyAttribute].AsString())
+	assert.Equal(t, peerSource, attributes[sourceAttribute].AsString())
+	assert.Equal(t, node2.HostPort(), attributes[sourceNodeAttribute].AsString())
+
+	t.Cleanup(func() {
+		assert.NoError(t, client.Close())
+		assert.NoError(t, node1.Stop(ctx))
+		assert.NoError(t, node2.Stop(ctx))
+		assert.NoError(t, sd1.Close())
+		assert.NoError(t, sd2.Close())
+		srv.Shutdown()
+	})
+}
+
+func TestClusterInfo(t *testing.T) {
+	ctx := context.Background()
+	// start the NATS server
+	srv := startNatsServer(t)
+
+	// create a cluster node1
+	node1, sd1 := startNode(t, srv.Addr().String())
+	require.NotNil(t, node1)
+
+	// create a cluster node2
+	node2, sd2 := startNode(t, srv.Addr().String())
+	require.NotNil(t, node2)
+
+	// wait for the nodes to sync
+	require.Eventually(t, func() bool {
+		return !node1.delegate.lastSyncTime(node2.HostPort()).IsZero()
+	}, 5*time.Second, 100*time.Millisecond)
+
+	address := lib.HostPort(node1.config.host, int(node1.config.port))
+	for _, endpoint := range []string{"healthz", "readyz", "cluster"} {
+		response, err := nethttp.Get(fmt.Sprintf("http://%s/%s", address, endpoint))
+		require.NoError(t, err)
+		assert.Equal(t, nethttp.StatusOK, response.StatusCode)
+		require.NoError(t, response.Body.Close())
+	}
+
+	info, err := node1.Client().ClusterInfo(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, info)
+	assert.True(t, info.Ready)
+	assert.Equal(t, node1.HostPort(), info.Self.DiscoveryAddress())
+	require.Len(t, info.Peers, 1)
+	peer := info.Peers[0]
+	assert.Equal(t, node2.HostPort(), peer.Member.DiscoveryAddress())
+	assert.False(t, peer.LastSyncTime.IsZero())
+
+	t.Cleanup(func() {
+		assert.NoError(t, node1.Stop(ctx))
+		assert.NoError(t, node2.Stop(ctx))
+		assert.NoError(t, sd1.Close())
+		assert.NoError(t, sd2.Close())
+		srv.Shutdown()
+	})
+}
+
+func TestJoin(t *testing.T) {
+	ctx := context.Background()
+	t.Run("With discovery retries", func(t *testing.T) {
+		provider := new(mocks.Provider)
+		provider.EXPECT().Initialize().Return(nil)
+		provider.EXPECT().Register().Return(nil)
+		provider.EXPECT().DiscoverPeers().Return(nil, errors.New("discovery not ready")).Times(2)
+		provider.EXPECT().DiscoverPeers().Return(nil, nil)
+		provider.EXPECT().Deregister().Return(nil)
+		provider.EXPECT().Close().Return(nil)
+
+		node, err := newNode(joinConfig(provider))
+		require.NoError(t, err)
+		require.NoError(t, node.Start(ctx))
+		assert.True(t, node.started.Load())
+		assert.NoError(t, node.Stop(ctx))
+		provider.AssertNumberOfCalls(t, "DiscoverPeers", 3)
+	})
+	t.Run("With join failure", func(t *testing.T) {
+		provider := new(mocks.Provider)
+		provider.EXPECT().Initialize().Return(nil)
+		provider.EXPECT().Register().Return(nil)
+		provider.EXPECT().DiscoverPeers().Return(nil, errors.New("discovery not ready"))
+
+		node, err := newNode(joinConfig(provider))
+		require.NoError(t, err)
+		require.Error(t, node.Start(ctx))
+		assert.False(t, node.started.Load())
+		provider.AssertNumberOfCalls(t, "DiscoverPeers", 3)
+		if node.httpServer != nil {
+			assert.NoError(t, node.httpServer.Shutdown(ctx))
+		}
+	})
+	t.Run("With single node start", func(t *testing.T) {
+		discoveries := atomic.NewInt32(0)
+		provider := new(mocks.Provider)
+		provider.EXPECT().Initialize().Return(nil)
+		provider.EXPECT().Register().Return(nil)
+		provider.EXPECT().DiscoverPeers().Return(nil, errors.New("discovery not ready")).Run(func() { discoveries.Inc() })
+		provider.EXPECT().Deregister().Return(nil)
+		provider.EXPECT().Close().Return(nil)
+
+		node, err := newNode(joinConfig(provider).WithSingleNodeStart(true))
+		require.NoError(t, err)
+		require.NoError(t, node.Start(ctx))
+		assert.True(t, node.started.Load())
+
+		// the node keeps discovering its peers in the background
+		lib.Pause(time.Second)
+		assert.Greater(t, discoveries.Load(), int32(3))
+		assert.NoError(t, node.Stop(ctx))
+	})
+}
+
+func TestRediscovery(t *testing.T) {
+	ctx := context.Background()
+
+	// node1 never discovers any peer
+	provider1 := new(mocks.Provider)
+	provider1.EXPECT().Initialize().Return(nil)
+	provider1.EXPECT().Register().Return(nil)
+	provider1.EXPECT().DiscoverPeers().Return(nil, nil)
+	provider1.EXPECT().Deregister().Return(nil)
+	provider1.EXPECT().Close().Return(nil)
+
+	node1, err := newNode(joinConfig(provider1))
+	require.NoError(t, err)
+	require.NoError(t, node1.Start(ctx))
+
+	// node2 only discovers node1 after it has started
+	provider2 := new(mocks.Provider)
+	provider2.EXPECT().Initialize().Return(nil)
+	provider2.EXPECT().Register().Return(nil)
+	provider2.EXPECT().DiscoverPeers().Return(nil, nil).Once()
+	provider2.EXPECT().DiscoverPeers().Return([]string{node1.HostPort()}, nil)
+	provider2.EXPECT().Deregister().Return(nil)
+	provider2.EXPECT().Close().Return(nil)
+
+	node2, err := newNode(joinConfig(provider2).WithRediscoveryInterval(200 * time.Millisecond))
+	require.NoError(t, err)
+	require.NoError(t, node2.Start(ctx))
+
+	require.Eventually(t, func() bool {
+		peers, err := node1.Peers()
+		return err == nil && len(peers) == 1 && peers[0].DiscoveryAddress() == node2.HostPort()
+	}, 5*time.Second, 100*time.Millisecond)
+
+	t.Cleanup(func() {
+		assert.NoError(t, node2.Stop(ctx))
+		assert.NoError(t, node1.Stop(ctx))
+	})
+}
+
+func TestRediscoveryByHostname(t *testing.T) {
+	ctx := context.Background()
+
+	provider1 := new(mocks.Provider)
+	provider1.EXPECT().Initialize().Return(nil)
+	provider1.EXPECT().Register().Return(nil)
+	provider1.EXPECT().DiscoverPeers().Return(nil, nil)
+	provider1.EXPECT().Deregister().Return(nil)
+	provider1.EXPECT().Close().Return(nil)
+
+	node1, err := newNode(joinConfig(provider1))
+	require.NoError(t, err)
+	require.NoError(t, node1.Start(ctx))
+
+	// node2 discovers itself and node1 by hostname once it has started
+	_, port1, err := net.SplitHostPort(node1.HostPort())
+	require.NoError(t, err)
+
+	provider2 := new(mocks.Provider)
+	config2 := joinConfig(provider2).WithRediscoveryInterval(200 * time.Millisecond)
+	peers := []string{
+		net.JoinHostPort("localhost", port1),
+		net.JoinHostPort("localhost", strconv.Itoa(int(config2.discoveryPort))),
+	}
+	provider2.EXPECT().Initialize().Return(nil)
+	provider2.EXPECT().Register().Return(nil)
+	provider2.EXPECT().DiscoverPeers().Return(nil, nil).Once()
+	provider2.EXPECT().DiscoverPeers().Return(peers, nil)
+	provider2.EXPECT().Deregister().Return(nil)
+	provider2.EXPECT().Close().Return(nil)
+
+	node2, err := newNode(config2)
+	require.NoError(t, err)
+	require.NoError(t, node2.Start(ctx))
+
+	require.Eventually(t, func() bool {
+		peers, err := node1.Peers()
+		return err == nil && len(peers) == 1 && peers[0].DiscoveryAddress() == node2.HostPort()
+	}, 5*time.Second, 100*time.Millisecond)
+
+	// the peers discovered by hostname are recognized as members and not joined again
+	joined, err := node2.discoverAndJoin()
+	require.NoError(t, err)
+	assert.Zero(t, joined)
+
+	t.Cleanup(func() {
+		assert.NoError(t, node2.Stop(ctx))
+		assert.NoError(t, node1.Stop(ctx))
+	})
+}
+
+func joinConfig(provider discovery.Provider) *Config {
+	ports := dynaport.Get(2)
+	return NewConfig().
+		WithDiscoveryProvider(provider).
+		WithHost("127.0.0.1").
+		WithPort(uint16(ports[0])).
+		WithDiscoveryPort(uint16(ports[1])).
+		WithLogger(log.DiscardLogger).
+		WithShutdownTimeout(time.Second).
+		WithMaxJoinAttempts(3).
+		WithJoinRetryInterval(100 * time.Millisecond)
+}
+
 func startNatsServer(t *testing.T) *natsserver.Server {
 	t.Helper()
 	serv, err := natsserver.NewServer(&natsserver.Options{
@@ -215,7 +1133,7 @@ func startNatsServer(t *testing.T) *natsserver.Server {
 	return serv
 }
 
-func startNode(t *testing.T, serverAddr string) (*Node, discovery.Provider) {
+func startNode(t *testing.T, serverAddr string, opts ...func(config *Config)) (*Node, discovery.Provider) {
 	ctx := context.TODO()
 	logger := log.DefaultLogger
 
@@ -242,19 +1160,33 @@ func startNode(t *testing.T, serverAddr string) (*Node, discovery.Provider) {
 	// create the instance of provider
 	provider := nats.NewDiscovery(&config, nats.WithLogger(logger))
 
-	node, _ := newNode(&Config{
-		provider:          provider,
-		port:              uint16(clientPort),
-		discoveryPort:     uint16(gossipPort),
-		shutdownTimeout:   time.Second,
-		logger:            logger,
-		host:              host,
-		syncInterval:      500 * time.Millisecond,
-		joinRetryInterval: 500 * time.Millisecond,
-		maxJoinAttempts:   5,
-		cookie:            cookie,
-		secretKeys:        []string{b64},
-	})
+	nodeConfig := &Config{
+		provider:                provider,
+		port:                    uint16(clientPort),
+		discoveryPort:           uint16(gossipPort),
+		shutdownTimeout:         time.Second,
+		logger:                  logger,
+		host:                    host,
+		syncInterval:            500 * time.Millisecond,
+		joinRetryInterval:       500 * time.Millisecond,
+		maxJoinAttempts:         5,
+		cookie:                  cookie,
+		secretKeys:              []string{b64},
+		placement:               NewZoneAwarePlacement(),
+		serverReadTimeout:       3 * time.Second,
+		serverReadHeaderTimeout: time.Second,
+		serverWriteTimeout:      time.Second,
+		serverIdleTimeout:       time.Minute,
+		stateChunkSize:          defaultStateChunkSize,
+		tombstoneRetention:      time.Hour,
+	}
+
+	// apply the test specific settings
+	for _, opt := range opts {
+		opt(nodeConfig)
+	}
+
+	node, _ := newNode(nodeConfig)
 
 	// start the node
 	require.NoError(t, node.Start(ctx))
@@ -0,0 +1,144 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2024 Arsene Tochemey Gandote
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package gokv
+
+import (
+	"hash/fnv"
+	"sort"
+)
+
+// PlacementStrategy defines how the replicas of a key are placed on the cluster members
+type PlacementStrategy interface {
+	// Place returns at most replicas members holding the given key, ordered by preference.
+	// The same key, members and replicas must always lead to the same placement.
+	Place(key string, members []*Member, replicas int) []*Member
+}
+
+// zoneAwarePlacement places the replicas using rendezvous hashing
+// and spreads them across distinct zones
+type zoneAwarePlacement struct{}
+
+// enforce compilation error
+var _ PlacementStrategy = (*zoneAwarePlacement)(nil)
+
+// NewZoneAwarePlacement creates a PlacementStrategy based on rendezvous hashing that
+// places the replicas of a key in distinct zones. When there are fewer zones than replicas,
+// the remaining replicas are placed on the highest ranked members of the already used zones.
+// Members without a zone are considered to be in the same zone.
+func NewZoneAwarePlacement() PlacementStrategy {
+	return &zoneAwarePlacement{}
+}
+
+// Place returns the members holding the replicas of the given key
+func (p *zoneAwarePlacement) Place(key string, members []*Member, replicas int) []*Member {
+	if replicas <= 0 || len(members) == 0 {
+		return nil
+	}
+
+	if replicas > len(members) {
+		replicas = len(members)
+	}
+
+	ranked := rank(key, members)
+	placement := make([]*Member, 0, replicas)
+	zones := make(map[string]struct{}, replicas)
+	var skipped []*Member
+
+	// first pick the highest ranked member of every zone
+	for _, member := range ranked {
+		if len(placement) == replicas {
+			break
+		}
+
+		if _, ok := zones[member.Zone]; ok {
+			skipped = append(skipped, member)
+			continue
+		}
+
+		zones[member.Zone] = struct{}{}
+		placement = append(placement, member)
+	}
+
+	// then complete with the highest ranked remaining members
+	for _, member := range skipped {
+		if len(placement) == replicas {
+			break
+		}
+		placement = append(placement, member)
+	}
+
+	return placement
+}
+
+// PreferZone returns the given members with the ones running in the given zone first.
+// The relative order of the members is preserved. This is used to read from a
+// same-zone replica and cut cross-zone traffic.
+func PreferZone(zone string, members []*Member) []*Member {
+	preferred := make([]*Member, 0, len(members))
+	var others []*Member
+	for _, member := range members {
+		if member.Zone == zone {
+			preferred = append(preferred, member)
+			continue
+		}
+		others = append(others, member)
+	}
+	return append(preferred, others...)
+}
+
+// rank sorts the members by their rendezvous score for the given key
+func rank(key string, members []*Member) []*Member {
+	type scored struct {
+		member *Member
+		score  uint64
+	}
+
+	scores := make([]scored, 0, len(members))
+	for _, member := range members {
+		scores = append(scores, scored{member: member, score: score(key, member.Name)})
+	}
+
+	sort.Slice(scores, func(i, j int) bool {
+		if scores[i].score == scores[j].score {
+			return scores[i].member.Name < scores[j].member.Name
+		}
+		return scores[i].score > scores[j].score
+	})
+
+	ranked := make([]*Member, 0, len(scores))
+	for _, s := range scores {
+		ranked = append(ranked, s.member)
+	}
+	return ranked
+}
+
+// score computes the rendezvous score of the given member for the given key
+func score(key, member string) uint64 {
+	hasher := fnv.New64a()
+	_, _ = hasher.Write([]byte(key))
+	_, _ = hasher.Write([]byte{0})
+	_, _ = hasher.Write([]byte(member))
+	return hasher.Sum64()
+}
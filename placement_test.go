@@ -0,0 +1,110 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2024 Arsene Tochemey Gandote
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package gokv
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestZoneAwarePlacement(t *testing.T) {
+	// nine members spread across three zones
+	var members []*Member
+	for i := 0; i < 9; i++ {
+		members = append(members, &Member{
+			Name: fmt.Sprintf("node-%d", i),
+			Zone: fmt.Sprintf("zone-%d", i%3),
+		})
+	}
+
+	placement := NewZoneAwarePlacement()
+	t.Run("With replicas in distinct zones", func(t *testing.T) {
+		for i := 0; i < 100; i++ {
+			key := fmt.Sprintf("key-%d", i)
+			replicas := placement.Place(key, members, 3)
+			require.Len(t, replicas, 3)
+
+			zones := make(map[string]struct{})
+			for _, replica := range replicas {
+				zones[replica.Zone] = struct{}{}
+			}
+			assert.Len(t, zones, 3)
+		}
+	})
+	t.Run("With deterministic placement", func(t *testing.T) {
+		reversed := make([]*Member, 0, len(members))
+		for i := len(members) - 1; i >= 0; i-- {
+			reversed = append(reversed, members[i])
+		}
+		assert.Equal(t, placement.Place("key", members, 3), placement.Place("key", reversed, 3))
+	})
+	t.Run("With fewer zones than replicas", func(t *testing.T) {
+		replicas := placement.Place("key", members, 5)
+		require.Len(t, replicas, 5)
+
+		zones := make(map[string]struct{})
+		for _, replica := range replicas[:3] {
+			zones[replica.Zone] = struct{}{}
+		}
+		assert.Len(t, zones, 3)
+	})
+	t.Run("With more replicas than members", func(t *testing.T) {
+		assert.Len(t, placement.Place("key", members, 20), len(members))
+	})
+	t.Run("With no replicas", func(t *testing.T) {
+		assert.Empty(t, placement.Place("key", members, 0))
+		assert.Empty(t, placement.Place("key", nil, 3))
+	})
+	t.Run("With minimal movement", func(t *testing.T) {
+		// removing a member only moves the keys it was holding
+		moved := 0
+		for i := 0; i < 100; i++ {
+			key := fmt.Sprintf("key-%d", i)
+			before := placement.Place(key, members, 1)[0]
+			after := placement.Place(key, members[1:], 1)[0]
+			if before.Name != after.Name {
+				assert.Equal(t, members[0].Name, before.Name)
+				moved++
+			}
+		}
+		assert.Less(t, moved, 100)
+	})
+}
+
+func TestPreferZone(t *testing.T) {
+	members := []*Member{
+		{Name: "a", Zone: "zone-1"},
+		{Name: "b", Zone: "zone-2"},
+		{Name: "c", Zone: "zone-1"},
+	}
+
+	preferred := PreferZone("zone-2", members)
+	require.Len(t, preferred, 3)
+	assert.Equal(t, []string{"b", "a", "c"}, []string{preferred[0].Name, preferred[1].Name, preferred[2].Name})
+	assert.Equal(t, members, PreferZone("zone-3", members))
+}
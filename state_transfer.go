@@ -0,0 +1,100 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2024 Arsene Tochemey Gandote
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package gokv
+
+import (
+	"context"
+	"fmt"
+
+	"connectrpc.com/connect"
+
+	"github.com/tochemey/gokv/internal/http"
+	"github.com/tochemey/gokv/internal/internalpb"
+	"github.com/tochemey/gokv/internal/internalpb/internalpbconnect"
+)
+
+// defaultStateChunkSize is the default size in bytes of a state transfer chunk
+const defaultStateChunkSize = 1 << 20
+
+// StreamState streams the node local state in chunks to a peer.
+// The local state is served as soon as the node listens, to let the peers bootstrap from it.
+// The stream ends when the node stops
+// nolint
+func (node *Node) StreamState(ctx context.Context, request *connect.Request[internalpb.StreamStateRequest], stream *connect.ServerStream[internalpb.StreamStateResponse]) error {
+	return node.delegate.streamState(node.config.stateChunkSize, func(chunk *internalpb.StreamStateResponse) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if node.delegate.transfersCtx.Err() != nil {
+			return connect.NewError(connect.CodeUnavailable, ErrNodeNotStarted)
+		}
+		return stream.Send(chunk)
+	})
+}
+
+// fetchState transfers the state of the peer described by the given digest over its StreamState endpoint
+func (node *Node) fetchState(ctx context.Context, digest *internalpb.NodeDigest) (*internalpb.NodeState, error) {
+	service := internalpbconnect.NewKVServiceClient(node.transferClient,
+		http.URL(digest.GetHost(), int(digest.GetPort())),
+		connect.WithInterceptors(node.config.interceptors...))
+
+	stream, err := service.StreamState(ctx, connect.NewRequest(new(internalpb.StreamStateRequest)))
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+
+	var state *internalpb.NodeState
+	for stream.Receive() {
+		chunk := stream.Msg()
+		if state == nil {
+			state = &internalpb.NodeState{
+				NodeId:     chunk.GetNodeId(),
+				Entries:    make(map[string]*internalpb.Entry, len(chunk.GetEntries())),
+				Generation: chunk.GetGeneration(),
+				Version:    chunk.GetVersion(),
+			}
+		}
+
+		// every chunk belongs to the same snapshot
+		if chunk.GetNodeId() != state.GetNodeId() || !sameVersion(chunk, state) {
+			return nil, errInvalidPeerState
+		}
+
+		for _, entry := range chunk.GetEntries() {
+			state.Entries[entry.GetKey()] = entry
+		}
+	}
+
+	if err := stream.Err(); err != nil {
+		return nil, err
+	}
+
+	if state == nil {
+		return nil, fmt.Errorf("%w: no state received from %s", errInvalidPeerState, digest.GetNodeId())
+	}
+	return state, nil
+}
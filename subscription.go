@@ -0,0 +1,176 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2024 Arsene Tochemey Gandote
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package gokv
+
+import (
+	"sync"
+
+	"go.uber.org/atomic"
+)
+
+// defaultEventsBufferSize is the default size of a subscription events buffer
+const defaultEventsBufferSize = 256
+
+// DropPolicy defines what a subscription does with an event
+// when its buffer is full
+type DropPolicy int
+
+const (
+	// DropOldest drops the oldest buffered event to make room for the new one
+	DropOldest DropPolicy = iota
+	// DropNewest drops the new event and keeps the buffered ones
+	DropNewest
+)
+
+// SubscriptionOption configures a Subscription
+type SubscriptionOption interface {
+	// Apply sets the Option value of a subscription.
+	Apply(subscription *Subscription)
+}
+
+var _ SubscriptionOption = SubscriptionOptionFunc(nil)
+
+// SubscriptionOptionFunc implements the SubscriptionOption interface.
+type SubscriptionOptionFunc func(subscription *Subscription)
+
+// Apply applies the subscription option
+func (f SubscriptionOptionFunc) Apply(subscription *Subscription) {
+	f(subscription)
+}
+
+// WithEventsBufferSize sets the number of events the subscription buffers
+// before applying its drop policy. Defaults to 256
+func WithEventsBufferSize(size int) SubscriptionOption {
+	return SubscriptionOptionFunc(func(subscription *Subscription) {
+		if size > 0 {
+			subscription.bufferSize = size
+		}
+	})
+}
+
+// WithDropPolicy sets the subscription drop policy. Defaults to DropOldest
+func WithDropPolicy(policy DropPolicy) SubscriptionOption {
+	return SubscriptionOptionFunc(func(subscription *Subscription) {
+		subscription.dropPolicy = policy
+	})
+}
+
+// WithEventTypes restricts the subscription to the given event types.
+// By default, a subscription receives all the events
+func WithEventTypes(types ...EventType) SubscriptionOption {
+	return SubscriptionOptionFunc(func(subscription *Subscription) {
+		subscription.eventTypes = make(map[EventType]struct{}, len(types))
+		for _, eventType := range types {
+			subscription.eventTypes[eventType] = struct{}{}
+		}
+	})
+}
+
+// Subscription defines a subscription to the cluster events.
+// Every subscription has its own bounded buffer so that a slow subscriber
+// neither blocks the node nor the other subscribers.
+type Subscription struct {
+	mu         *sync.Mutex
+	events     chan *Event
+	bufferSize int
+	dropPolicy DropPolicy
+	eventTypes map[EventType]struct{}
+	dropped    *atomic.Uint64
+	closed     bool
+}
+
+// newSubscription creates an instance of Subscription
+func newSubscription(opts ...SubscriptionOption) *Subscription {
+	subscription := &Subscription{
+		mu:         new(sync.Mutex),
+		bufferSize: defaultEventsBufferSize,
+		dropPolicy: DropOldest,
+		dropped:    atomic.NewUint64(0),
+	}
+
+	for _, opt := range opts {
+		opt.Apply(subscription)
+	}
+
+	subscription.events = make(chan *Event, subscription.bufferSize)
+	return subscription
+}
+
+// Events returns the subscription events channel.
+// The channel is closed when the subscription is cancelled or the node stops
+func (subscription *Subscription) Events() <-chan *Event {
+	return subscription.events
+}
+
+// Dropped returns the number of events dropped because the subscription buffer was full
+func (subscription *Subscription) Dropped() uint64 {
+	return subscription.dropped.Load()
+}
+
+// publish delivers the event to the subscriber without blocking
+func (subscription *Subscription) publish(event *Event) {
+	subscription.mu.Lock()
+	defer subscription.mu.Unlock()
+
+	if subscription.closed {
+		return
+	}
+
+	if len(subscription.eventTypes) > 0 {
+		if _, ok := subscription.eventTypes[event.Type]; !ok {
+			return
+		}
+	}
+
+	for {
+		select {
+		case subscription.events <- event:
+			return
+		default:
+		}
+
+		if subscription.dropPolicy == DropNewest {
+			subscription.dropped.Inc()
+			return
+		}
+
+		// make room for the new event by dropping the oldest one
+		select {
+		case <-subscription.events:
+			subscription.dropped.Inc()
+		default:
+		}
+	}
+}
+
+// close closes the subscription events channel
+func (subscription *Subscription) close() {
+	subscription.mu.Lock()
+	if !subscription.closed {
+		subscription.closed = true
+		close(subscription.events)
+	}
+	subscription.mu.Unlock()
+}
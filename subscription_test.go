@@ -0,0 +1,80 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2024 Arsene Tochemey Gandote
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package gokv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscription(t *testing.T) {
+	t.Run("With default settings", func(t *testing.T) {
+		subscription := newSubscription()
+		assert.Equal(t, defaultEventsBufferSize, cap(subscription.events))
+		assert.Equal(t, DropOldest, subscription.dropPolicy)
+		assert.Empty(t, subscription.eventTypes)
+	})
+	t.Run("With drop oldest policy", func(t *testing.T) {
+		subscription := newSubscription(WithEventsBufferSize(2), WithDropPolicy(DropOldest))
+		for _, key := range []string{"a", "b", "c"} {
+			subscription.publish(&Event{Type: KeyAdded, Key: key})
+		}
+
+		assert.EqualValues(t, 1, subscription.Dropped())
+		assert.Equal(t, "b", (<-subscription.Events()).Key)
+		assert.Equal(t, "c", (<-subscription.Events()).Key)
+	})
+	t.Run("With drop newest policy", func(t *testing.T) {
+		subscription := newSubscription(WithEventsBufferSize(2), WithDropPolicy(DropNewest))
+		for _, key := range []string{"a", "b", "c"} {
+			subscription.publish(&Event{Type: KeyAdded, Key: key})
+		}
+
+		assert.EqualValues(t, 1, subscription.Dropped())
+		assert.Equal(t, "a", (<-subscription.Events()).Key)
+		assert.Equal(t, "b", (<-subscription.Events()).Key)
+	})
+	t.Run("With event types", func(t *testing.T) {
+		subscription := newSubscription(WithEventTypes(KeyDeleted))
+		subscription.publish(&Event{Type: KeyAdded, Key: "a"})
+		subscription.publish(&Event{Type: KeyDeleted, Key: "a"})
+
+		require.Len(t, subscription.Events(), 1)
+		event := <-subscription.Events()
+		assert.Equal(t, KeyDeleted, event.Type)
+	})
+	t.Run("With closed subscription", func(t *testing.T) {
+		subscription := newSubscription()
+		subscription.close()
+		// closing twice and publishing after close are no-op
+		subscription.close()
+		subscription.publish(&Event{Type: KeyAdded, Key: "a"})
+
+		_, ok := <-subscription.Events()
+		assert.False(t, ok)
+	})
+}
@@ -0,0 +1,104 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2024 Arsene Tochemey Gandote
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package gokv
+
+import (
+	"connectrpc.com/connect"
+	"connectrpc.com/otelconnect"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+const (
+	// localSource defines the hit source of an entry found in the node local state
+	localSource = "local"
+	// peerSource defines the hit source of an entry found in a peer state
+	peerSource = "peer"
+)
+
+// the attribute keys used to annotate the spans
+var (
+	keyAttribute        = attribute.Key("gokv.key")
+	nodeAttribute       = attribute.Key("gokv.node")
+	sourceAttribute     = attribute.Key("gokv.source")
+	sourceNodeAttribute = attribute.Key("gokv.source.node")
+	entriesAttribute    = attribute.Key("gokv.entries")
+	joinAttribute       = attribute.Key("gokv.join")
+)
+
+// newTracer creates a tracer from the given tracer provider.
+// It returns a no-op tracer when the provider is not set
+func newTracer(provider trace.TracerProvider) trace.Tracer {
+	if provider == nil {
+		provider = noop.NewTracerProvider()
+	}
+	return provider.Tracer(instrumentationName)
+}
+
+// newTelemetryInterceptor creates the connect interceptor that records the calls metrics and spans.
+// The trace context is propagated using the W3C trace context format.
+// It returns nil when neither the meter provider nor the tracer provider is set
+func newTelemetryInterceptor(meterProvider metric.MeterProvider, tracerProvider trace.TracerProvider, opts ...otelconnect.Option) (connect.Interceptor, error) {
+	if meterProvider == nil && tracerProvider == nil {
+		return nil, nil
+	}
+
+	if meterProvider != nil {
+		opts = append(opts, otelconnect.WithMeterProvider(meterProvider))
+	} else {
+		opts = append(opts, otelconnect.WithoutMetrics())
+	}
+
+	if tracerProvider != nil {
+		opts = append(opts,
+			otelconnect.WithTracerProvider(tracerProvider),
+			otelconnect.WithPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})))
+	} else {
+		opts = append(opts, otelconnect.WithoutTracing())
+	}
+
+	return otelconnect.NewInterceptor(opts...)
+}
+
+// hitAttributes returns the span attributes of an entry found in the given node state
+func hitAttributes(self, source string) []attribute.KeyValue {
+	if source == self {
+		return []attribute.KeyValue{sourceAttribute.String(localSource), sourceNodeAttribute.String(source)}
+	}
+	return []attribute.KeyValue{sourceAttribute.String(peerSource), sourceNodeAttribute.String(source)}
+}
+
+// endSpan records the given error when set and ends the span
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,217 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2024 Arsene Tochemey Gandote
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package gokv
+
+import (
+	"context"
+	nethttp "net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"connectrpc.com/connect"
+
+	"github.com/tochemey/gokv/internal/internalpb"
+	"github.com/tochemey/gokv/internal/internalpb/internalpbconnect"
+)
+
+// defaultWatchBufferSize is the size of a watch stream buffer
+const defaultWatchBufferSize = 1024
+
+// watcher holds the changes to stream to a Watch caller
+type watcher struct {
+	mu      sync.Mutex
+	keys    map[string]struct{}
+	changes chan *internalpb.WatchResponse
+	// lagging is set when the watcher buffer overflowed
+	lagging bool
+	closed  bool
+}
+
+// newWatcher creates an instance of watcher for the given keys.
+// All the keys are watched when none is given
+func newWatcher(keys []string) *watcher {
+	watched := make(map[string]struct{}, len(keys))
+	for _, key := range keys {
+		watched[key] = struct{}{}
+	}
+	return &watcher{
+		keys:    watched,
+		changes: make(chan *internalpb.WatchResponse, defaultWatchBufferSize),
+	}
+}
+
+// notify buffers the given change when its key is watched.
+// The watcher is closed when its buffer is full since its caller would miss the change
+func (w *watcher) notify(change *internalpb.WatchResponse) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return
+	}
+
+	if len(w.keys) > 0 {
+		if _, ok := w.keys[change.GetKey()]; !ok {
+			return
+		}
+	}
+
+	select {
+	case w.changes <- change:
+	default:
+		w.lagging = true
+		w.closed = true
+		close(w.changes)
+	}
+}
+
+// close closes the watcher. It is safe to call it more than once
+func (w *watcher) close() {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.changes)
+	}
+	w.mu.Unlock()
+}
+
+// isLagging returns true when the watcher has been closed because of a buffer overflow
+func (w *watcher) isLagging() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.lagging
+}
+
+// Watch streams the changes of the cluster keys as seen by the node.
+// The stream starts with a ready event and ends with a ResourceExhausted error when the caller
+// does not keep up with the changes
+// nolint
+func (node *Node) Watch(ctx context.Context, request *connect.Request[internalpb.WatchRequest], stream *connect.ServerStream[internalpb.WatchResponse]) error {
+	if !node.started.Load() {
+		return connect.NewError(connect.CodeFailedPrecondition, ErrNodeNotStarted)
+	}
+
+	watcher := newWatcher(request.Msg.GetKeys())
+	if !node.addWatcher(watcher) {
+		return connect.NewError(connect.CodeFailedPrecondition, ErrNodeNotStarted)
+	}
+	defer node.removeWatcher(watcher)
+
+	if err := stream.Send(&internalpb.WatchResponse{Type: internalpb.WatchEventType_WATCH_EVENT_TYPE_READY}); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case change, ok := <-watcher.changes:
+			if !ok {
+				if watcher.isLagging() {
+					return connect.NewError(connect.CodeResourceExhausted, ErrWatchLagging)
+				}
+				return nil
+			}
+
+			if err := stream.Send(change); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// addWatcher registers the given watcher. It returns false when the node is stopped
+func (node *Node) addWatcher(watcher *watcher) bool {
+	node.eventsLock.Lock()
+	defer node.eventsLock.Unlock()
+	if node.watchersClosed {
+		return false
+	}
+
+	watchers := append(slices.Clip(*node.watchers.Load()), watcher)
+	node.watchers.Store(&watchers)
+	return true
+}
+
+// removeWatcher unregisters and closes the given watcher
+func (node *Node) removeWatcher(removed *watcher) {
+	node.eventsLock.Lock()
+	watchers := slices.DeleteFunc(slices.Clone(*node.watchers.Load()), func(current *watcher) bool {
+		return current == removed
+	})
+	node.watchers.Store(&watchers)
+	node.eventsLock.Unlock()
+	removed.close()
+}
+
+// notifyWatchers delivers the given changes to all the watchers.
+// A watcher removed meanwhile is closed and ignores the changes
+func (node *Node) notifyWatchers(changes ...*keyChange) {
+	watchers := *node.watchers.Load()
+	if len(watchers) == 0 {
+		return
+	}
+
+	for _, change := range changes {
+		response := &internalpb.WatchResponse{
+			Type:  internalpb.WatchEventType_WATCH_EVENT_TYPE_PUT,
+			Key:   change.key,
+			Entry: change.entry,
+		}
+
+		if change.entry == nil {
+			response.Type = internalpb.WatchEventType_WATCH_EVENT_TYPE_DELETE
+		}
+
+		for _, watcher := range watchers {
+			watcher.notify(response)
+		}
+	}
+}
+
+// closeWatchers closes all the watchers and ends their streams
+func (node *Node) closeWatchers() {
+	node.eventsLock.Lock()
+	node.watchersClosed = true
+	for _, watcher := range *node.watchers.Swap(new([]*watcher)) {
+		watcher.close()
+	}
+	node.eventsLock.Unlock()
+}
+
+// withoutStreamDeadlines lifts the http server read and write timeouts of the Watch streams
+// which are long-lived by design and of the state transfers which can be large.
+// The other calls keep the server timeouts
+func withoutStreamDeadlines(handler nethttp.Handler) nethttp.Handler {
+	return nethttp.HandlerFunc(func(writer nethttp.ResponseWriter, request *nethttp.Request) {
+		switch request.URL.Path {
+		case internalpbconnect.KVServiceWatchProcedure, internalpbconnect.KVServiceStreamStateProcedure:
+			controller := nethttp.NewResponseController(writer)
+			_ = controller.SetReadDeadline(time.Time{})
+			_ = controller.SetWriteDeadline(time.Time{})
+		}
+		handler.ServeHTTP(writer, request)
+	})
+}
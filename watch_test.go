@@ -0,0 +1,58 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2024 Arsene Tochemey Gandote
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package gokv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tochemey/gokv/internal/internalpb"
+)
+
+func TestWatcher(t *testing.T) {
+	t.Run("With keys", func(t *testing.T) {
+		watcher := newWatcher([]string{"key"})
+		watcher.notify(&internalpb.WatchResponse{Key: "other"})
+		watcher.notify(&internalpb.WatchResponse{Key: "key"})
+		assert.Len(t, watcher.changes, 1)
+		assert.Equal(t, "key", (<-watcher.changes).GetKey())
+	})
+	t.Run("With a full buffer", func(t *testing.T) {
+		watcher := newWatcher(nil)
+		for i := 0; i <= defaultWatchBufferSize; i++ {
+			watcher.notify(&internalpb.WatchResponse{Key: "key"})
+		}
+		assert.True(t, watcher.isLagging())
+
+		// the buffered changes are drained before the channel closes
+		received := 0
+		for range watcher.changes {
+			received++
+		}
+		assert.Equal(t, defaultWatchBufferSize, received)
+		watcher.close()
+	})
+}